@@ -0,0 +1,197 @@
+package banquet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Schema is an allowlist for Authorize: the tables a query may read, each with the columns it
+// may reference. A "*" entry allows every column of the table, including a SELECT *. Table
+// keys are matched against QualifiedTable, so main.users must be listed as such.
+type Schema map[string][]string
+
+// AuthorizationError names the first identifier Authorize rejected.
+type AuthorizationError struct {
+	Table  string // The table the query reads.
+	Field  string // Clause that referenced Name: Table, Select, Where, OrderBy, GroupBy, Having or RawSQL.
+	Name   string // The disallowed table or column, or a raw fragment that can't be checked.
+	Reason string
+}
+
+func (e *AuthorizationError) Error() string {
+	if e.Field == "Table" {
+		return fmt.Sprintf("banquet: table %q: %s", e.Name, e.Reason)
+	}
+	return fmt.Sprintf("banquet: %s %q on table %q: %s", e.Field, e.Name, e.Table, e.Reason)
+}
+
+// Authorize checks that b only reads a table in allowed and only references its allowed
+// columns: selected columns (including the arguments of functions and arithmetic), path
+// conditions, sorts, the group-by and structured having params (count>5, sum(total)>100).
+// It returns an *AuthorizationError for the first identifier that isn't allowed. Names match
+// case-insensitively, as SQLite resolves them.
+//
+// Free-form SQL can't be checked, so a where query param, a having param that isn't in the
+// structured form and a rawsql param are rejected outright. A flat file has no table until one
+// is inferred; call sqlite.ApplyInferredTable first and list the inferred name.
+//
+// A From subquery is checked against allowed, and the outer query may then reference any
+// column of the derived table, which only exposes what the subquery was allowed to read.
+func Authorize(b *Banquet, allowed Schema) error {
+	if b.From != nil {
+		if err := Authorize(b.From, allowed); err != nil {
+			return err
+		}
+		outer := *b
+		outer.From = nil
+		return Authorize(&outer, Schema{b.QualifiedTable(): {"*"}})
+	}
+	table := b.QualifiedTable()
+	var columns []string
+	found := false
+	for name, cols := range allowed {
+		if table != "" && strings.EqualFold(name, table) {
+			columns, found = cols, true
+			break
+		}
+	}
+	if !found {
+		return &AuthorizationError{Field: "Table", Name: table, Reason: "not allowed"}
+	}
+	deny := func(field, name, reason string) error {
+		return &AuthorizationError{Table: table, Field: field, Name: name, Reason: reason}
+	}
+	check := func(field string, names ...string) error {
+		for _, name := range names {
+			if !allowsColumn(columns, name) {
+				return deny(field, name, "not allowed")
+			}
+		}
+		return nil
+	}
+
+	if b.RawSQL != "" {
+		return deny("RawSQL", b.RawSQL, "raw SQL can't be checked")
+	}
+	selected := b.Select
+	if len(selected) == 0 {
+		selected = []string{"*"}
+	}
+	for _, col := range selected {
+		if err := check("Select", columnRefs(col)...); err != nil {
+			return err
+		}
+	}
+	if b.URL != nil && len(rawQueryValues(b.RawQuery, "where")) > 0 || len(b.Conditions) == 0 && b.Where != "" {
+		where := b.Where
+		if b.URL != nil {
+			if raw := rawQueryValues(b.RawQuery, "where"); len(raw) > 0 {
+				where = raw[0]
+			}
+		}
+		return deny("Where", where, "raw where can't be checked")
+	}
+	for _, cond := range b.Conditions {
+		if err := check("Where", conditionColumns(cond)...); err != nil {
+			return err
+		}
+	}
+	sorts := b.Sorts
+	if len(sorts) == 0 && b.OrderBy != "" {
+		sorts = []OrderTerm{{Column: b.OrderBy}}
+	}
+	for _, sort := range sorts {
+		if sort.IsPosition() {
+			// A position orders by a selected column, checked above
+			continue
+		}
+		if err := check("OrderBy", columnRefs(sort.Column)...); err != nil {
+			return err
+		}
+	}
+	if b.GroupBy != "" {
+		for _, col := range strings.Split(b.GroupBy, ",") {
+			if err := check("GroupBy", strings.TrimSpace(col)); err != nil {
+				return err
+			}
+		}
+	}
+	if b.Having != "" {
+		having := []string{b.Having}
+		if b.URL != nil {
+			having = rawQueryValues(b.RawQuery, "having")
+		}
+		for _, h := range having {
+			m := havingCondition.FindStringSubmatch(strings.TrimSpace(h))
+			if m == nil {
+				return deny("Having", h, "raw having can't be checked")
+			}
+			var refs []string
+			if !strings.EqualFold(m[1], "count") {
+				refs = columnRefs(m[1])
+			}
+			if ref, ok := havingColumn(m[3]); ok {
+				refs = append(refs, ref)
+			}
+			if err := check("Having", refs...); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// allowsColumn reports whether name is in columns, or columns holds "*".
+func allowsColumn(columns []string, name string) bool {
+	for _, col := range columns {
+		if col == "*" || strings.EqualFold(col, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnRefs returns the columns col reads: the operands of arithmetic, the arguments of a
+// function call such as upper(name) or sum(total) (count(*) reads none) or col itself.
+// Literals are skipped.
+func columnRefs(col string) []string {
+	col = strings.TrimSpace(col)
+	if operands, _, ok := arithmetic(col); ok {
+		var refs []string
+		for _, operand := range operands {
+			if !numberLiteral.MatchString(operand) {
+				refs = append(refs, columnRefs(operand)...)
+			}
+		}
+		return refs
+	}
+	open := strings.IndexByte(col, '(')
+	if open > 0 && strings.HasSuffix(col, ")") && isBareIdentifier(col[:open]) {
+		var refs []string
+		for _, arg := range splitColumns(col[open+1 : len(col)-1]) {
+			arg = strings.TrimSpace(arg)
+			if arg == "*" || literalArg.MatchString(arg) {
+				continue
+			}
+			refs = append(refs, columnRefs(arg)...)
+		}
+		return refs
+	}
+	return []string{col}
+}
+
+// conditionColumns returns the columns c compares, including @col references and OR alternatives.
+func conditionColumns(c Condition) []string {
+	var cols []string
+	for _, alt := range c.Or {
+		cols = append(cols, conditionColumns(alt)...)
+	}
+	if c.Column != "" {
+		cols = append(cols, c.Column)
+	}
+	cols = append(cols, c.Columns...)
+	if c.IsColumn {
+		cols = append(cols, c.Value)
+	}
+	return cols
+}
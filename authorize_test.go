@@ -0,0 +1,86 @@
+package banquet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	allowed := Schema{
+		"users":  {"id", "name", "age", "status"},
+		"orders": {"*"},
+	}
+	tests := []struct {
+		url   string
+		field string // empty when allowed
+		name  string
+	}{
+		{"data.sqlite;users;id,name,-age,status!=banned", "", ""},
+		{"data.sqlite;users;upper(name),age*2?groupby=status&having=count>1&having=max(age)>30", "", ""},
+		{"data.sqlite;USERS;ID,Name", "", ""},
+		{"data.sqlite;orders", "", ""},
+		{"data.sqlite;orders;id?where=total>5", "Where", "total>5"},
+
+		{"data.sqlite;secrets;id", "Table", "secrets"},
+		{"data.sqlite;main.users;id", "Table", "main.users"},
+		{"users.csv;;id", "Table", ""},
+		{"data.sqlite;users", "Select", "*"},
+		{"data.sqlite;users;id,ssn", "Select", "ssn"},
+		{"data.sqlite;users;id,lower(ssn)", "Select", "ssn"},
+		{"data.sqlite;users;id,ssn!=x", "Where", "ssn"},
+		{"data.sqlite;users;id,age>@ssn", "Where", "ssn"},
+		{"data.sqlite;users;id,status=a|ssn=b", "Where", "ssn"},
+		{"data.sqlite;users;id,-ssn", "OrderBy", "ssn"},
+		{"data.sqlite;users;id?orderby=ssn:desc", "OrderBy", "ssn"},
+		{"data.sqlite;users;id?groupby=ssn", "GroupBy", "ssn"},
+		{"data.sqlite;users;id?having=max(ssn)>0", "Having", "ssn"},
+		{"data.sqlite;users;id?having=count>@ssn", "Having", "ssn"},
+		{"data.sqlite;users;id?having=1=1%20OR%20ssn>0", "Having", "1=1 OR ssn>0"},
+		{"data.sqlite;users;id?rawsql=SELECT%20ssn%20FROM%20users", "RawSQL", "SELECT ssn FROM users"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		err = Authorize(b, allowed)
+		if tt.field == "" {
+			if err != nil {
+				t.Errorf("Authorize(%q) = %v, want nil", tt.url, err)
+			}
+			continue
+		}
+		var aerr *AuthorizationError
+		if !errors.As(err, &aerr) || aerr.Field != tt.field || aerr.Name != tt.name {
+			t.Errorf("Authorize(%q) = %v, want %s %q rejected", tt.url, err, tt.field, tt.name)
+		}
+	}
+}
+
+func TestAuthorizeSubquery(t *testing.T) {
+	allowed := Schema{"users": {"id", "country"}}
+	inner, err := ParseBanquet("data.sqlite;users;id,country?groupby=country")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	outer := Subquery(inner, "by_country")
+	outer.Conditions = []Condition{{Column: "country", Operator: OpEq, Value: "nz"}}
+	if err := Authorize(outer, allowed); err != nil {
+		t.Errorf("Authorize() = %v, want nil", err)
+	}
+
+	// The subquery is held to the schema
+	bad, err := ParseBanquet("data.sqlite;users;id,ssn")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	var authErr *AuthorizationError
+	if err := Authorize(Subquery(bad, "u"), allowed); !errors.As(err, &authErr) || authErr.Name != "ssn" {
+		t.Errorf("Authorize(bad subquery) = %v, want ssn rejected", err)
+	}
+	// Raw SQL over the derived table still can't be checked
+	outer.Where, outer.Conditions = "id IN (SELECT id FROM secrets)", nil
+	if err := Authorize(outer, allowed); !errors.As(err, &authErr) || authErr.Field != "Where" {
+		t.Errorf("Authorize(raw where) = %v, want Where rejected", err)
+	}
+}
@@ -5,7 +5,7 @@
 // Banquet Classifications & URL Structure:
 //
 // 1. Flat (1-tier): path/to/dataset;;Column
-// 2. Nested Table (2-tier): path/to/dataset;Table
+// 2. Nested Table (2-tier): path/to/dataset;Table (for flat files such as csv, the second tier is Columns)
 // 3. Nested Column (2-tier): path/to/dataset;Table;Column
 //
 // Fallback Convention (semicolon-less):
@@ -13,13 +13,23 @@
 //
 // Supported Prefixes/Suffixes:
 // - Sort: +column (ASC), -column (DESC)
-// - Slice: [start:end] (translated to LIMIT/OFFSET)
+// - Exclusion: !column (all columns but column; needs a column list to compose, see ExpandSelect)
+// - Slice: [start:end] (translated to LIMIT/OFFSET), [N] (shorthand for [0:N])
+// - Page tokens: limit:N and offset:N, used when no query param or slice sets the value
+// - Top: ?top=N reads the first N rows, overriding limit and any slice
+// - Functions: upper(col), +date(col) and the other ScalarFunctions wrap a column in the select list or a sort
 package banquet
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/url"
+	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -40,21 +50,108 @@ func IsVerbose() bool {
 // It extends url.URL with SQL-like clauses derived from the path and query parameters.
 type Banquet struct {
 	*url.URL
-	Where         string
-	Table         string   // Table name derived from the URL path.
-	Select        []string // Columns to select. Empty or ["*"] implies all columns.
-	SortDirection string   // "ASC" or "DESC".
+	Where         string      // Query where param ANDed with the rendered Conditions.
+	Table         string      // Table name derived from the URL path.
+	TableSource   TableSource // How Table was decided, e.g. TableHeuristic when it was guessed.
+	Schema        string      // Schema qualifying Table, from a schema.table table tier.
+	Select        []string    // Columns to select. Empty or ["*"] implies all columns.
+	SelectAll     bool        // The URL asked for * explicitly (users;*, users;id,* or ?select=*) rather than it being inferred.
+	Exclude       []string    // Columns to leave out of the selection, from !col tokens; see ExpandSelect.
+	Distinct      bool        // SELECT DISTINCT, from the distinct=true query param.
+	SortDirection string      // "ASC" or "DESC".
 	Limit         string
 	Offset        string
 	GroupBy       string
 	Having        string
 	OrderBy       string
-	DataSetPath   string // Path to the source dataset file (e.g., .csv, .sqlite).
-
-	ColumnPath string // The remaining path segment containing columns, sort intructions, or conditions.
+	RawSQL        string       // The rawsql query param, used as is by composers that allow it; see ErrRawSQL.
+	Sorts         []OrderTerm  // All ORDER BY terms in order; OrderBy/SortDirection mirror the first.
+	Conditions    []Condition  // Structured path conditions; Where holds them rendered for SQLite.
+	DataSetPath   string       // Path to the source dataset file (e.g., .csv, .sqlite).
+	From          *Banquet     // Subquery read in place of a table, aliased as Table; see Subquery.
+	Extra         []QueryParam // Query params Banquet doesn't interpret (e.g. orderid=1), in URL order, for handlers to forward.
+
+	ColumnPath   string   // The remaining path segment containing columns, sort intructions, or conditions.
+	OutputFormat string   // "csv" or "json" from a suffix on the last explicit tier, e.g. data.sqlite;users.csv.
+	Auth         Auth     // Credentials derived from the URL userinfo.
+	FromEnd      bool     // Slice uses negative indices counting from the end; see ResolveFromEnd.
+	Errors       []error  // Problems tolerated during parsing, e.g. a malformed slice.
+	Warnings     []string // Human readable notes on input skipped by tolerant parsing, e.g. limit value "abc" ignored.
 	// fields below are for internal use
-	rawurl string
-	path   string
+	rawurl      string
+	path        string
+	sliceStart  string // raw slice bounds kept for ResolveFromEnd
+	sliceEnd    string
+	queryWhere  string // the where param as parsed, after StripComments and QuoteInLists
+	parsedWhere string // Where as parsed, to tell filters a caller adds to Where apart
+}
+
+// QueryParam is one decoded key=value pair of the query string.
+type QueryParam struct {
+	Key   string
+	Value string
+}
+
+// TableSource records how Banquet.Table was decided.
+type TableSource int
+
+const (
+	// TableUnset means there is no table yet.
+	TableUnset TableSource = iota
+	// TableExplicit is a table named by a semicolon tier (data.sqlite;users) or the table query param.
+	TableExplicit
+	// TableHeuristic is a table guessed from a semicolon-less path, e.g. users in data.sqlite/users.
+	TableHeuristic
+	// TableInferredDefault is a table filled in by a composer, e.g. sqlite_master or tb0;
+	// see sqlite.ApplyInferredTable.
+	TableInferredDefault
+)
+
+// warnf records a Warnings entry.
+func (b *Banquet) warnf(format string, args ...any) {
+	b.Warnings = append(b.Warnings, fmt.Sprintf(format, args...))
+}
+
+// QualifiedTable returns Table prefixed with Schema when one is set, e.g. public.users.
+func (b *Banquet) QualifiedTable() string {
+	if b.Schema == "" || b.Table == "" {
+		return b.Table
+	}
+	return b.Schema + "." + b.Table
+}
+
+// Subquery returns a query reading inner as a derived table named alias, which composers
+// render as SELECT * FROM (inner) AS "alias". Clauses added to the result apply to the
+// derived table, e.g. Conditions filtering the groups of an aggregated inner query.
+func Subquery(inner *Banquet, alias string) *Banquet {
+	return &Banquet{From: inner, Table: alias, TableSource: TableExplicit, Select: []string{"*"}}
+}
+
+// Auth holds credentials carried in the URL userinfo, which Banquet repurposes to signal authentication.
+// Userinfo without a colon (token@host) is treated as a bearer token; Username is still set for compatibility.
+type Auth struct {
+	Username string
+	Password string
+	Token    string
+}
+
+// parseAuth derives Auth from url userinfo.
+func parseAuth(u *url.Userinfo) Auth {
+	if u == nil {
+		return Auth{}
+	}
+	if p, ok := u.Password(); ok {
+		return Auth{Username: u.Username(), Password: p}
+	}
+	return Auth{Username: u.Username(), Token: u.Username()}
+}
+
+// OrderTerm is a single ORDER BY column with its direction. Column may also be a function call
+// such as length(name) or a 1-based select list position such as 2 (from +2); see IsPosition.
+type OrderTerm struct {
+	Column    string
+	Direction string // "ASC", "DESC", or "" when unspecified.
+	Nulls     string // "FIRST", "LAST", or "" for the engine default; from a :nullsfirst or :nullslast suffix.
 }
 
 const (
@@ -62,49 +159,158 @@ const (
 	ASC = "+"
 	// DESC is the prefix token to signal descending sort order.
 	DESC = "-"
+	// TablesTable is the introspection pseudo table listing a database's tables, as in
+	// data.sqlite;*tables. Composers that support it query the engine catalog instead.
+	TablesTable = "*tables"
 )
 
+// CleanStep is a single normalization applied to a raw URL before url.Parse.
+type CleanStep func(rawurl string) string
+
 // CleanUrl prepares a raw URL string for standard parsing.
 // It trims leading slashes (unless it's the root path) and ensures standard scheme formatting.
 func CleanUrl(rawurl string) string {
+	return CleanUrlWith(rawurl, TrimLeadingSlash, NormalizeScheme, ProtectColonSegment)
+}
+
+// CleanUrlWith applies steps to rawurl in order, letting callers pick which of the
+// CleanUrl normalizations they want.
+func CleanUrlWith(rawurl string, steps ...CleanStep) string {
+	for _, step := range steps {
+		rawurl = step(rawurl)
+	}
+	return rawurl
+}
+
+// TrimLeadingSlash drops a single leading slash. The root path "/" becomes ".".
+func TrimLeadingSlash(rawurl string) string {
 	// housekeeping before url.Parse
 	if rawurl == "/" {
 		return "."
 	}
-	rawurl = strings.TrimPrefix(rawurl, "/")
+	return strings.TrimPrefix(rawurl, "/")
+}
 
-	// Ensure standard scheme format (e.g., gs:/ -> gs://) for proper authority parsing
+// NormalizeScheme ensures standard scheme format (e.g., gs:/ -> gs://) for proper authority parsing.
+func NormalizeScheme(rawurl string) string {
 	if idx := strings.Index(rawurl, ":/"); idx != -1 {
 		if !strings.HasPrefix(rawurl[idx:], "://") {
 			rawurl = strings.Replace(rawurl, ":/", "://", 1)
 		}
-	} else {
-		// If NO scheme separator :// is present, checks for colons in what would be the first path segment.
-		// Go's url.Parse will error if the first segment contains a colon (e.g. chars:chars) thinking it's a scheme.
-		// We encounter this with slice notation in filenames or windows paths if not careful.
-		// Fix: If no scheme, valid path chars shouldn't be interpreted as scheme.
-		// Prepend ./ makes it a clear relative path
-		if !strings.Contains(rawurl, "://") && strings.Contains(rawurl, ":") {
-			// Check if slash appears before colon
-			slashIdx := strings.Index(rawurl, "/")
-			colonIdx := strings.Index(rawurl, ":")
-			if colonIdx < slashIdx || slashIdx == -1 {
-				// Colon appears before any slash. This triggers "first path segment..." error
-				return "./" + rawurl
-			}
-		}
 	}
+	return rawurl
+}
 
+// ProtectColonSegment prefixes ./ when a scheme-less URL has a colon in its first path segment.
+func ProtectColonSegment(rawurl string) string {
+	// Go's url.Parse will error if the first segment contains a colon (e.g. chars:chars) thinking it's a scheme.
+	// We encounter this with slice notation in filenames or windows paths if not careful.
+	// Fix: If no scheme, valid path chars shouldn't be interpreted as scheme.
+	// Prepend ./ makes it a clear relative path
+	if strings.Contains(rawurl, ":/") || !strings.Contains(rawurl, ":") {
+		return rawurl
+	}
+	// Check if slash appears before colon
+	slashIdx := strings.Index(rawurl, "/")
+	colonIdx := strings.Index(rawurl, ":")
+	if colonIdx < slashIdx || slashIdx == -1 {
+		// Colon appears before any slash. This triggers "first path segment..." error
+		return "./" + rawurl
+	}
 	return rawurl
 }
 
+// ParseOptions tunes the tolerant defaults of ParseBanquet. The zero value matches ParseBanquet.
+type ParseOptions struct {
+	// TolerantSlice ignores malformed slice notation, recording the *ValidationError in
+	// Banquet.Errors and a warning. By default the parse fails with it.
+	TolerantSlice bool
+
+	// IncludeSortInSelect keeps +col/-col sort columns in the select list when explicit columns are given.
+	// The select_sort=true query param enables it per request.
+	IncludeSortInSelect bool
+
+	// Strict fails the parse with ErrAmbiguousPath when the table/column split would rely on
+	// the semicolon-less heuristic (e.g. dataset.sqlite/tableorcolumn) instead of explicit tiers,
+	// with the ValidateGrouping error when selected columns are missing from the GROUP BY, and
+	// with the *ValidationError of a malformed tuple IN condition. By default such a condition is
+	// dropped and the error recorded in Banquet.Errors.
+	Strict bool
+
+	// LegacyCaret restores the sort prefixes of older clients: ^col sorts descending and
+	// !^col ascending. ColumnPath holds the translated +/- form. By default both are literal column names.
+	LegacyCaret bool
+
+	// DedupeSelect drops repeated select columns, keeping the first of each in order, so
+	// users;id,name,id selects id, name. Names compare exactly. By default repeats are kept.
+	DedupeSelect bool
+
+	// MaxLimit caps Limit, recording a warning when a request asks for more. 0 means no cap.
+	// A URL without a limit, e.g. ?offset=5, gets MaxLimit as its limit. So does a negative
+	// slice (FromEnd), and ResolveFromEnd keeps the resolved limit under it.
+	MaxLimit int
+
+	// DefaultLimit is used when the URL sets no limit. 0 leaves Limit empty. An explicit
+	// limit=all or limit=-1 still means every row (capped by MaxLimit).
+	DefaultLimit int
+
+	// StripComments removes -- and /* */ comments from Where and Having, e.g. a client's
+	// where=age>18--note reads age>18. Comment markers inside quoted strings are kept.
+	// It is a sanitizer, not a substitute for Validate.
+	StripComments bool
+
+	// QuoteInLists quotes the bareword values of IN lists in the where param, see QuoteInLists.
+	// A bareword can't be told apart from a column, so by default lists are left as written.
+	QuoteInLists bool
+
+	// TierSeparator replaces ; between the dataset, table and column tiers, e.g. "::" reads
+	// data.sqlite::users::id like data.sqlite;users;id. A backslash escapes a literal separator.
+	// Path and Unparse use the canonical ; form. Empty means ;.
+	TierSeparator string
+}
+
+// ErrRawSQL is returned by composers asked to run a rawsql query param without being allowed to,
+// e.g. sqlite.Options.AllowRawSQL. Raw SQL bypasses every check, so it is off by default.
+var ErrRawSQL = errors.New("banquet: rawsql is not allowed")
+
+// ErrNoTable is returned by composers when a Banquet names no table and none can be inferred,
+// e.g. data.sqlite;;id,name, rather than emitting a FROM clause without a table.
+var ErrNoTable = errors.New("banquet: no table named or inferred")
+
+// ErrUnresolvedSlice is returned by strict composers for a slice counting from the end, e.g.
+// users[-10:], which needs the row count. Call ResolveFromEnd before composing.
+var ErrUnresolvedSlice = errors.New("banquet: slice from the end needs ResolveFromEnd before composing")
+
+// ErrAmbiguousPath is returned in Strict mode when the table cannot be told apart from a column
+// without guessing. Use explicit tiers (dataset;table;columns) instead.
+var ErrAmbiguousPath = errors.New("banquet: ambiguous path, use ';' to separate dataset, table and columns")
+
 // ParseBanquet parses a raw URL string into a functioning Banquet object.
 // It handles cleaning, URL parsing, and decomposition into Dataset, Table, and Column path segments.
 func ParseBanquet(rawurl string) (*Banquet, error) {
+	return parse(context.Background(), rawurl, ParseOptions{})
+}
+
+// ParseBanquetWithOptions is like ParseBanquet but honors opts.
+func ParseBanquetWithOptions(rawurl string, opts ParseOptions) (*Banquet, error) {
+	return parse(context.Background(), rawurl, opts)
+}
+
+// ParseBanquetContext is like ParseBanquet but checks ctx between the major parse phases,
+// returning ctx.Err() promptly on cancellation or deadline.
+func ParseBanquetContext(ctx context.Context, rawurl string) (*Banquet, error) {
+	return parse(ctx, rawurl, ParseOptions{})
+}
+
+func parse(ctx context.Context, rawurl string, opts ParseOptions) (*Banquet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if verbose {
 		log.Printf("[BANQUET] Parsing URL: %s", rawurl)
 	}
 	// Standardize/Clean the URL (trim leading slash, fix scheme)
+	written := rawurl
 	rawurl = CleanUrl(rawurl)
 
 	if verbose {
@@ -119,19 +325,72 @@ func ParseBanquet(rawurl string) (*Banquet, error) {
 		return nil, err
 	}
 
+	if opts.TierSeparator != "" && opts.TierSeparator != ";" {
+		u.Path = translateTierSeparator(u.Path, opts.TierSeparator)
+		if u.RawPath != "" {
+			u.RawPath = translateTierSeparator(u.RawPath, opts.TierSeparator)
+		}
+		// CleanUrl's ./ guards a colon in the first segment, which may have been the separator
+		if trimmed, ok := strings.CutPrefix(u.Path, "./"); ok && !strings.HasPrefix(TrimLeadingSlash(written), "./") &&
+			ProtectColonSegment(trimmed) == trimmed {
+			u.Path = trimmed
+			u.RawPath = strings.TrimPrefix(u.RawPath, "./")
+		}
+	}
+
 	b := &Banquet{
 		URL:    u,
+		Auth:   parseAuth(u.User),
 		rawurl: rawurl,
 	}
 
 	b.DataSetPath, b.Table, b.ColumnPath = parseDataSetColumnPath(b.Path)
+	// %2C is a literal comma only in the column tier, see escapedComma
+	if path := escapeEncodedCommas(u.EscapedPath(), b.Path); path != b.Path {
+		_, _, b.ColumnPath = parseDataSetColumnPath(path)
+	}
+	if file := strayDataSetFile(b.DataSetPath); file != "" && hasTiers(b.Path) {
+		b.warnf("dataset %q continues past %s; the table tier starts at the first ';'", b.DataSetPath, file)
+	}
+	// A .csv or .json suffix on the last explicit tier asks for an output format, e.g. data.sqlite;users.csv
+	if tiers := len(splitTiers(b.Path)); tiers == 3 || tiers == 2 && b.ColumnPath != "" {
+		b.ColumnPath, b.OutputFormat = cutOutputFormat(b.ColumnPath)
+	} else if tiers == 2 {
+		b.Table, b.OutputFormat = cutOutputFormat(b.Table)
+	}
+	if opts.LegacyCaret {
+		b.ColumnPath = translateLegacyCaret(b.ColumnPath)
+	}
+	// Slices are read from the whole path; a bare N:M token counts only when no bracketed slice is present
+	slicePath := b.Path
+	if _, _, ok := sliceBounds(b.Path); !ok {
+		var bare string
+		if b.ColumnPath, bare = cutBareSlice(b.ColumnPath); bare != "" {
+			slicePath += bare
+		}
+	}
 	if verbose {
 		log.Printf("[BANQUET] DataSetPath: %s, Table: %q, ColumnPath: %s", b.DataSetPath, b.Table, b.ColumnPath)
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Parse the query once and share it across the clause parsers
+	query, _ := url.ParseQuery(b.RawQuery)
 
-	// Table parsing logic - fallback to heuristic only if not explicitly set via semicolon
-	if b.Table == "" {
+	// Table parsing logic - fallback to heuristic only if not explicitly set via semicolon.
+	// Explicit tiers (any semicolon) never fall back, so "file.csv;name" keeps name as a column.
+	// A table query param stands in for the heuristic but never overrides a semicolon tier.
+	tableSource := TableExplicit
+	if b.Table == "" && !hasTiers(b.Path) && query.Get("table") != "" {
+		b.Table = strings.TrimSpace(query.Get("table"))
+	} else if b.Table == "" && !hasTiers(b.Path) {
 		b.Table = parseTable(b.ColumnPath)
+		tableSource = TableHeuristic
+		if opts.Strict && b.Table != "" {
+			return nil, fmt.Errorf("%w: %q", ErrAmbiguousPath, b.Path)
+		}
 		if verbose {
 			log.Printf("[BANQUET] Table identified via heuristic: %s", b.Table)
 		}
@@ -142,20 +401,68 @@ func ParseBanquet(rawurl string) (*Banquet, error) {
 		b.Table = b.Table[:idx]
 	}
 
-	// Populate fields using private parsers
-	b.Select = ParseSelect(b.ColumnPath)
+	// schema.table in the table tier
+	if idx := strings.LastIndex(b.Table, "."); idx > 0 && idx < len(b.Table)-1 {
+		b.Schema, b.Table = b.Table[:idx], b.Table[idx+1:]
+	}
+	if b.Table != "" {
+		b.TableSource = tableSource
+	}
+
+	if query.Get("select_sort") == "true" {
+		opts.IncludeSortInSelect = true
+	}
+
+	// Classify the column path once and populate fields from the result
+	cols := scanColumnPath(b.ColumnPath)
+	b.Select = cols.selectList()
+	b.Exclude = cols.excludes
+	if opts.IncludeSortInSelect && len(cols.selects) > 0 {
+		b.Select = cols.withSorts
+	}
 	if verbose {
 		log.Printf("[BANQUET] Selected columns: %v", b.Select)
 	}
 
 	// Fix: if Select is just the Table name, change to *
-	if len(b.Select) == 1 && b.Select[0] == b.Table {
+	if len(b.Select) == 1 && b.Select[0] == b.QualifiedTable() {
 		b.Select = []string{"*"}
 	}
 
+	// The select query param applies only when the path names no columns
+	if sel := query.Get("select"); sel != "" && b.Select[0] == "*" {
+		var selects []string
+		for _, col := range splitColumns(sel) {
+			if col = strings.TrimSpace(unescapeComma(col)); col != "" {
+				selects = append(selects, col)
+			}
+		}
+		if len(selects) > 0 {
+			b.Select = selects
+		}
+	}
+	if opts.DedupeSelect {
+		b.Select = dedupe(b.Select)
+	}
+	explicitAll := slices.Contains(cols.selects, "*") || strings.TrimSpace(query.Get("select")) == "*"
+	b.SelectAll = explicitAll && slices.Contains(b.Select, "*")
+	b.Distinct, _ = strconv.ParseBool(query.Get("distinct"))
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Combine query params 'where' and path conditions
-	queryWhere := parseWhere(b.RawQuery)
-	pathWhere := parsePathConditions(b.ColumnPath)
+	queryWhere := parseWhere(b.RawQuery, opts.StripComments, opts.QuoteInLists)
+	b.queryWhere = queryWhere
+	pathWhere := cols.where()
+	b.Conditions = cols.conditions
+	for _, err := range cols.errs {
+		if opts.Strict {
+			return nil, err
+		}
+		b.Errors = append(b.Errors, err)
+	}
 
 	if pathWhere != "" {
 		if queryWhere != "" {
@@ -167,28 +474,126 @@ func ParseBanquet(rawurl string) (*Banquet, error) {
 		b.Where = queryWhere
 	}
 
+	b.parsedWhere = b.Where
 	if verbose && b.Where != "" {
 		log.Printf("[BANQUET] effective WHERE: %s", b.Where)
 	}
 
-	b.GroupBy = ParseGroupBy(b.Path, b.RawQuery)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.GroupBy = parseGroupBy(b.Path, query)
+	if err := ValidateGrouping(b); err != nil {
+		if opts.Strict {
+			return nil, err
+		}
+		b.Errors = append(b.Errors, err)
+	}
+
+	if _, _, _, err := parseSliceBounds(slicePath); err != nil {
+		if !opts.TolerantSlice {
+			return nil, err
+		}
+		b.Errors = append(b.Errors, err)
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			b.warnf("slice %s ignored: %s", verr.Value, verr.Reason)
+		}
+	}
 
-	// Passing b.Path to parseLimit allows finding slice anywhere.
-	b.Limit = parseLimit(b.RawQuery, b.Path)
-	b.Offset = parseOffset(b.RawQuery, b.Path)
-	b.Having = parseHaving(b.RawQuery)
-	if ob, dir := parseOrderBy(b.ColumnPath, b.RawQuery); ob != "" {
-		b.OrderBy = ob
-		if dir != "" {
-			b.SortDirection = dir
+	// top=N reads the first N rows and takes precedence over limit and any slice
+	top := query.Get("top")
+	if top != "" {
+		if _, _, ok := sliceBounds(slicePath); ok {
+			b.warnf("slice ignored: top=%s takes precedence", top)
+		}
+		slicePath = ""
+	}
+	// A limit or offset query param replaces the slice as a whole, so the pair never mixes sources
+	if query.Get("limit") != "" || query.Get("offset") != "" {
+		if start, end, ok := sliceBounds(slicePath); ok {
+			b.warnf("slice [%s:%s] ignored: limit and offset query params take precedence", start, end)
+		}
+		slicePath = ""
+	}
+
+	// Passing the whole path to parseLimit allows finding slice anywhere.
+	b.Limit = parseLimit(query, slicePath)
+	b.Offset = parseOffset(query, slicePath)
+	if top != "" {
+		b.Limit = top
+	}
+	// limit:N and offset:N path tokens apply when neither a query param nor a slice set the value
+	if b.Limit == "" {
+		b.Limit = cols.limit
+	}
+	if b.Offset == "" {
+		b.Offset = cols.offset
+	}
+	// limit=all and limit=-1 ask for every row explicitly: no LIMIT, and no DefaultLimit either
+	unlimited := strings.EqualFold(b.Limit, "all") || isNegative(b.Limit)
+	if unlimited {
+		if opts.MaxLimit > 0 {
+			b.warnf("limit %s capped at %d", b.Limit, opts.MaxLimit)
+		}
+		b.Limit = ""
+	}
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{{"limit", &b.Limit}, {"offset", &b.Offset}} {
+		if _, err := strconv.Atoi(*field.value); *field.value != "" && err != nil {
+			b.warnf("%s value %q ignored", field.name, *field.value)
+			*field.value = ""
+		}
+	}
+	if start, end, ok := sliceBounds(slicePath); ok && (isNegative(start) || isNegative(end)) &&
+		query.Get("limit") == "" && query.Get("offset") == "" {
+		b.FromEnd = true
+		b.sliceStart, b.sliceEnd = start, end
+	}
+	if b.Limit == "" && !b.FromEnd && !unlimited && opts.DefaultLimit > 0 {
+		b.Limit = strconv.Itoa(opts.DefaultLimit)
+	}
+	if b.Limit == "" && opts.MaxLimit > 0 {
+		b.Limit = strconv.Itoa(opts.MaxLimit)
+	}
+	if n, err := strconv.Atoi(b.Limit); err == nil && opts.MaxLimit > 0 && n > opts.MaxLimit {
+		b.warnf("limit %d capped at %d", n, opts.MaxLimit)
+		b.Limit = strconv.Itoa(opts.MaxLimit)
+	}
+	b.Having = parseHaving(b.RawQuery, opts.StripComments)
+	if raw := rawQueryValues(b.RawQuery, "rawsql"); len(raw) > 0 {
+		b.RawSQL = raw[0]
+	}
+	b.Extra = extraParams(b.RawQuery)
+	b.Sorts = parseSorts(cols, query)
+	var positionErr error
+	if b.Sorts, positionErr = checkPositions(b.Sorts, b.Select, b.Exclude); positionErr != nil {
+		if opts.Strict {
+			return nil, positionErr
+		}
+		b.Errors = append(b.Errors, positionErr)
+	}
+	if len(b.Sorts) > 0 {
+		b.OrderBy = b.Sorts[0].Column
+		if b.Sorts[0].Direction != "" {
+			b.SortDirection = b.Sorts[0].Direction
 		}
 	}
 
 	return b, nil
 }
 
+// FmtPrintln prints the raw URL fields of b to stdout; see Fprint.
 func FmtPrintln(b *Banquet) {
-	fmt.Printf(`rawurl: %s
+	Fprint(os.Stdout, b)
+}
+
+// Fprint writes the raw URL fields of b to w, one per line. Explain shows the parsed clauses instead.
+func Fprint(w io.Writer, b *Banquet) {
+	fmt.Fprintf(w, `rawurl: %s
 Scheme: %s
   Host:   %s
   DataSetPath: %s
@@ -199,14 +604,68 @@ Scheme: %s
 }
 
 func FmtSprintf(b *Banquet) string {
-	return fmt.Sprintf(`rawurl: %s\n
+	return fmt.Sprintf(`rawurl: %s
   S: %s H: %s DP: %sCP: %sRQ:%sTB:%q
 `, b.rawurl, b.Scheme, b.Host, b.DataSetPath, b.ColumnPath, b.RawQuery, b.Table)
 }
 
 // ParseNested extracts and parses a Banquet URL that wraps an inner URL.
 // This is common when a server receives a request like "http://localhost/gs://bucket/file...".
+// An inner URL ParseBanquet rejects, e.g. a malformed slice, returns its error.
 func ParseNested(rawURL string) (*Banquet, error) {
+	inner, err := peelEnvelope(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBanquet(inner)
+}
+
+// ParseNestedN is like ParseNested but keeps unwrapping envelopes while the inner path is itself
+// a URL with a scheme, e.g. http://gw/https://edge/gs:/bucket/file.csv, peeling at most maxDepth
+// envelopes. A maxDepth below 1 is treated as 1.
+func ParseNestedN(rawURL string, maxDepth int) (*Banquet, error) {
+	inner, err := peelEnvelope(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	for depth := 1; depth < maxDepth; depth++ {
+		next, err := peelEnvelope(inner)
+		if err != nil || !hasScheme(strings.TrimPrefix(next, "/")) {
+			break
+		}
+		// Each peel strips at least the scheme, so this cannot loop forever,
+		// but stop anyway if nothing changed.
+		if next == inner {
+			break
+		}
+		inner = next
+	}
+	return ParseBanquet(inner)
+}
+
+// IsNested reports whether rawURL wraps an inner URL, i.e. its path itself starts with a
+// scheme such as gs:/ or https://, as in http://localhost/gs://bucket/file.csv.
+func IsNested(rawURL string) bool {
+	inner, err := peelEnvelope(rawURL)
+	if err != nil {
+		return false
+	}
+	inner, _, _ = strings.Cut(inner, "?")
+	return hasScheme(strings.TrimPrefix(inner, "/"))
+}
+
+// ParseAuto parses rawURL with ParseNested when IsNested reports an inner URL and with
+// ParseBanquet otherwise.
+func ParseAuto(rawURL string) (*Banquet, error) {
+	if IsNested(rawURL) {
+		return ParseNested(rawURL)
+	}
+	return ParseBanquet(rawURL)
+}
+
+// peelEnvelope removes one outer envelope from rawURL and returns the inner path and query
+// exactly as they were on the wire.
+func peelEnvelope(rawURL string) (string, error) {
 	// 1. Parse the outer envelope
 	// If rawURL is http://localhost..., url.Parse works.
 	// If rawURL is just /http..., we need to trim prefix, but not if it's just "/"
@@ -218,7 +677,7 @@ func ParseNested(rawURL string) (*Banquet, error) {
 	if err != nil {
 		// If outer parse fails, we might just try to treat the whole thing as an inner url?
 		// But usually this means it's really malformed.
-		return nil, err
+		return "", err
 	}
 
 	// Key Fix: Use EscapedPath() to get the path segment exactly as it was on the wire (checking for %25 etc)
@@ -231,26 +690,102 @@ func ParseNested(rawURL string) (*Banquet, error) {
 	if outer.RawQuery != "" {
 		inner += "?" + outer.RawQuery
 	}
+	return inner, nil
+}
+
+// hasScheme reports whether s parses as a hierarchical URL with a scheme, like gs:/bucket
+// or https://host/path, as opposed to a column path such as name:desc.
+func hasScheme(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Opaque == ""
+}
+
+// defaultExtensions are the file extensions that mark the end of the dataset path.
+var defaultExtensions = []string{".zip", ".csv", ".sqlite", ".db", ".xlsx", ".json", ".html", ".txt", ".tsv", ".parquet"}
+
+var extensions = append([]string(nil), defaultExtensions...)
+
+// RegisterExtension adds a file extension (e.g. ".parquet") that marks the end of the dataset path.
+// Like SetVerbose, it is intended to be called during initialization.
+func RegisterExtension(ext string) {
+	ext = normalizeExtension(ext)
+	for _, e := range extensions {
+		if e == ext {
+			return
+		}
+	}
+	extensions = append(extensions, ext)
+}
 
-	b, err := ParseBanquet(inner)
-	if err != nil {
-		fmt.Printf("Error parsing inner URL '%s': %v. Continuing with raw URL.\n", inner, err)
-		// Return partial Banquet with just the raw extraction
-		return &Banquet{
-			URL:    &url.URL{Path: inner}, // Best effort
-			rawurl: inner,
-		}, nil
+// SetExtensions replaces the set of dataset file extensions. Passing nil restores the defaults.
+func SetExtensions(exts []string) {
+	if exts == nil {
+		extensions = append([]string(nil), defaultExtensions...)
+		return
 	}
-	return b, nil
+	extensions = make([]string, 0, len(exts))
+	for _, ext := range exts {
+		extensions = append(extensions, normalizeExtension(ext))
+	}
+}
+
+// Extensions returns a copy of the currently recognized dataset file extensions.
+func Extensions() []string {
+	return append([]string(nil), extensions...)
+}
+
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// hasDataSetExtension reports whether a path segment ends in a recognized dataset extension, ignoring case.
+func hasDataSetExtension(part string) bool {
+	lower := strings.ToLower(part)
+	// test.html is a page, not a dataset
+	if lower == "test.html" {
+		return false
+	}
+	for _, ext := range extensions {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// strayDataSetFile returns the segment of an explicit dataset tier that has a dataset extension
+// when the tier goes on past it, as in data.csv/extra;users. That usually means slash and
+// semicolon notation were mixed. A tier without any extension (a BigQuery dataset) or one inside
+// a .zip archive is fine and returns "".
+func strayDataSetFile(datasetPath string) string {
+	parts := strings.Split(strings.TrimSuffix(datasetPath, "/"), "/")
+	if len(parts) < 2 || hasDataSetExtension(parts[len(parts)-1]) {
+		return ""
+	}
+	for _, part := range parts[:len(parts)-1] {
+		if hasDataSetExtension(part) && !strings.HasSuffix(strings.ToLower(part), ".zip") {
+			return part
+		}
+	}
+	return ""
 }
 
 // parseDataSetColumnPath splits the raw path into dataset, table, and column segments.
 // It supports explicit tiers separated by semicolons (dataset;table;column) or implicit tiers based on file extensions.
+// For flat files (one table per file) the 2-tier form dataset;columns carries columns, not a table.
 func parseDataSetColumnPath(rawpath string) (datasetPath string, table string, columnPath string) {
 	// If rawpath contains semicolons, we use explicit tier parsing: dataset;table;columns
-	if strings.Contains(rawpath, ";") {
-		parts := strings.SplitN(rawpath, ";", 3)
+	if hasTiers(rawpath) {
+		parts := splitTiers(rawpath)
 		datasetPath = parts[0]
+		if len(parts) == 2 && isFlatFile(datasetPath) {
+			columnPath = parts[1]
+			return
+		}
 		if len(parts) > 1 {
 			table = parts[1]
 		}
@@ -263,23 +798,135 @@ func parseDataSetColumnPath(rawpath string) (datasetPath string, table string, c
 	// if there is no ";" then use existing file extension logic to split path into dataset path and column path
 	parts := strings.Split(rawpath, "/")
 	for i, part := range parts {
-		if strings.HasSuffix(part, ".zip") ||
-			strings.HasSuffix(part, ".csv") ||
-			strings.HasSuffix(part, ".sqlite") ||
-			strings.HasSuffix(part, ".db") ||
-			strings.HasSuffix(part, ".xlsx") ||
-			strings.HasSuffix(part, ".json") ||
-			(strings.HasSuffix(part, ".html") && part != "test.html") ||
-			strings.HasSuffix(part, ".txt") {
-
-			datasetPath = strings.Join(parts[:i+1], "/")
+		if hasDataSetExtension(part) {
+			datasetPath = unescapeTier(strings.Join(parts[:i+1], "/"))
 			if i+1 < len(parts) {
 				columnPath = strings.Join(parts[i+1:], "/")
 			}
 			return datasetPath, "", columnPath
 		}
 	}
-	return rawpath, "", ""
+	return unescapeTier(rawpath), "", ""
+}
+
+// escapedTierSeparator is a literal semicolon in a path, e.g. a file named weird\;name.csv.
+const escapedTierSeparator = `\;`
+
+// hasTiers reports whether path holds an unescaped ; tier separator.
+func hasTiers(path string) bool {
+	return strings.Contains(strings.ReplaceAll(path, escapedTierSeparator, ""), ";")
+}
+
+// splitTiers splits path into at most three tiers at unescaped semicolons and
+// unescapes \; in each tier.
+func splitTiers(path string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(path) && len(parts) < 2; i++ {
+		if path[i] == ';' && (i == 0 || path[i-1] != '\\') {
+			parts = append(parts, path[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, path[start:])
+	for i, part := range parts {
+		parts[i] = unescapeTier(part)
+	}
+	return parts
+}
+
+// translateTierSeparator rewrites the first two unescaped sep tier separators in path to ;,
+// escaping literal semicolons in the tiers before them and unescaping \sep. The column tier
+// is left as is, so sep may double as a column path operator such as | (OR).
+func translateTierSeparator(path, sep string) string {
+	var out strings.Builder
+	tiers := 0
+	for i := 0; i < len(path); {
+		switch {
+		case tiers == 2:
+			out.WriteString(path[i:])
+			i = len(path)
+		case strings.HasPrefix(path[i:], `\`+sep):
+			out.WriteString(sep)
+			i += 1 + len(sep)
+		case strings.HasPrefix(path[i:], sep):
+			out.WriteByte(';')
+			tiers++
+			i += len(sep)
+		case path[i] == ';':
+			out.WriteString(escapedTierSeparator)
+			i++
+		default:
+			out.WriteByte(path[i])
+			i++
+		}
+	}
+	return out.String()
+}
+
+// unescapeTier turns \; back into a literal semicolon.
+func unescapeTier(s string) string {
+	return strings.ReplaceAll(s, escapedTierSeparator, ";")
+}
+
+// escapeTier escapes literal semicolons in s so they are not read as tier separators.
+func escapeTier(s string) string {
+	return strings.ReplaceAll(s, ";", escapedTierSeparator)
+}
+
+// escapedComma is a literal comma in a column name, e.g. last\, first. On the wire %2C
+// reads the same way, so "last, first" can be selected as users;last%2C%20first,id.
+const escapedComma = `\,`
+
+// escapeEncodedCommas decodes the escaped path like path but keeps %2C as \, so the comma
+// isn't read as a column separator. path is returned when there is no %2C.
+func escapeEncodedCommas(escaped, path string) string {
+	if !strings.Contains(escaped, "%2C") && !strings.Contains(escaped, "%2c") {
+		return path
+	}
+	decoded, err := url.PathUnescape(strings.NewReplacer("%2C", escapedComma, "%2c", escapedComma).Replace(escaped))
+	if err != nil {
+		return path
+	}
+	return decoded
+}
+
+// unescapeComma turns \, back into a literal comma.
+func unescapeComma(s string) string {
+	return strings.ReplaceAll(s, escapedComma, ",")
+}
+
+// escapeComma escapes the commas in a column name that splitColumns would split on. Commas
+// inside a call such as substr(name,1,3) are left alone.
+func escapeComma(col string) string {
+	if len(splitColumns(col)) < 2 {
+		return col
+	}
+	return strings.ReplaceAll(col, ",", escapedComma)
+}
+
+// legacyDesc and legacyAsc match the old ^col and !^col sort prefixes at the start of a token.
+var (
+	legacyAsc  = regexp.MustCompile(`(^|[/,])!\^`)
+	legacyDesc = regexp.MustCompile(`(^|[/,])\^`)
+)
+
+// translateLegacyCaret rewrites !^col to +col and ^col to -col.
+func translateLegacyCaret(columnPath string) string {
+	columnPath = legacyAsc.ReplaceAllString(columnPath, "${1}"+ASC)
+	return legacyDesc.ReplaceAllString(columnPath, "${1}"+DESC)
+}
+
+// isFlatFile reports whether the dataset holds a single implicit table (e.g. csv),
+// as opposed to a container of named tables (e.g. sqlite). The extension matches in any case.
+func isFlatFile(datasetPath string) bool {
+	lower := strings.ToLower(datasetPath)
+	return strings.HasSuffix(lower, ".csv") ||
+		strings.HasSuffix(lower, ".txt") ||
+		strings.HasSuffix(lower, ".json") ||
+		strings.HasSuffix(lower, ".xlsx") ||
+		strings.HasSuffix(lower, ".tsv") ||
+		strings.HasSuffix(lower, ".parquet")
 }
 
 // getSegments identifies the part of the path that contains columns or conditions
@@ -295,8 +942,8 @@ func getSegments(columnPath string) []string {
 		if strings.Contains(part, ",") ||
 			strings.HasPrefix(part, ASC) ||
 			strings.HasPrefix(part, DESC) ||
-			strings.Contains(part, "!=") ||
-			(strings.HasPrefix(part, "[") && strings.Contains(part, ":")) {
+			hasOperator(part) ||
+			(strings.HasPrefix(part, "[") && looksLikeSlice(part)) {
 			firstClearSegment = i
 			break
 		}
@@ -310,142 +957,341 @@ func getSegments(columnPath string) []string {
 	return parts[len(parts)-1:]
 }
 
-func ParseSelect(columnPath string) []string {
-	segments := getSegments(columnPath)
-	if len(segments) == 0 {
-		return []string{"*"}
-	}
+// parsedColumns is the single-pass classification of a column path.
+type parsedColumns struct {
+	selects    []string    // Plain columns, in order.
+	conditions []Condition // Path conditions such as status!=active or a=1|b=2.
+	sorts      []OrderTerm // +/- prefixed columns, in order.
+	withSorts  []string    // Plain and sort columns interleaved in path order.
+	excludes   []string    // !col exclusions, in order.
+	errs       []error     // Malformed conditions that were dropped, e.g. an unbalanced tuple IN.
+	limit      string      // Value of a limit:N token.
+	offset     string      // Value of an offset:N token.
+}
+
+// pageToken matches the limit:N and offset:N column path tokens. Requiring the colon and a
+// digits-only value keeps them apart from columns named limit or offset.
+var pageToken = regexp.MustCompile(`^(limit|offset):(\d+)$`)
 
-	var collected []string
-	for _, segment := range segments {
+// scanColumnPath walks the column path once, classifying each comma separated token as a
+// condition (col!=val, col=val, col>val, ...), a sort (+col/-col), a limit:N/offset:N page token
+// or a selected column. Slice notation is stripped.
+func scanColumnPath(columnPath string) parsedColumns {
+	var pc parsedColumns
+	for _, segment := range getSegments(columnPath) {
 		// Do not clean the entire segment before splitting, as it might remove columns following slice notation.
 		// e.g., "id[0:10],name" -> cleanSegment="id" -> split -> ["id"] -> name is lost.
 		// Instead, split first, then clean.
-
 		if segment == "" {
 			continue
 		}
-
-		cols := strings.Split(segment, ",")
-		for _, col := range cols {
-			// Clean up slice notation from the column only if it looks like a slice
-			if idx := strings.Index(col, "["); idx != -1 {
-				if strings.Contains(col[idx:], ":") {
-					col = col[:idx]
+		// An unbalanced tuple IN can't be split into tokens reliably, so the segment is dropped
+		if isTupleIn(segment) && !balanced(segment) {
+			_, err := parseTupleIn(segment)
+			pc.errs = append(pc.errs, err)
+			continue
+		}
+		for _, token := range splitColumns(segment) {
+			if strings.HasPrefix(strings.TrimSpace(token), "(") && isTupleIn(token) {
+				if idx := strings.LastIndex(token, "["); idx != -1 && strings.HasSuffix(token, "]") && looksLikeSlice(token[idx:]) {
+					token = token[:idx]
+				}
+				if cond, err := parseTupleIn(token); err != nil {
+					pc.errs = append(pc.errs, err)
+				} else {
+					pc.conditions = append(pc.conditions, cond)
 				}
+				continue
 			}
-
-			// Basic cleanup
-			col = strings.TrimSpace(col)
-			if col == "" {
+			token = unescapeComma(token)
+			if hasOperator(token) {
+				// A trailing slice belongs to the whole path, not to the condition value
+				if idx := strings.LastIndex(token, "["); idx != -1 && strings.HasSuffix(token, "]") && looksLikeSlice(token[idx:]) {
+					token = token[:idx]
+				}
+				cond, ok, err := parseConditionGroup(token)
+				if err != nil {
+					pc.errs = append(pc.errs, err)
+				}
+				if ok {
+					pc.conditions = append(pc.conditions, cond)
+				}
 				continue
 			}
 
-			// Ignore conditions
-			if strings.Contains(col, "!=") {
+			col := strings.TrimSpace(token)
+			if m := pageToken.FindStringSubmatch(col); m != nil {
+				if m[1] == "limit" {
+					pc.limit = m[2]
+				} else {
+					pc.offset = m[2]
+				}
 				continue
 			}
-
-			// If it has a sort prefix, it's for ordering, not necessarily for selection.
+			// !col excludes a column. Only a leading ! followed by a name counts,
+			// so literal names such as !^col are still selected as is.
+			if len(col) > 1 && col[0] == '!' && isIdentChar(col[1]) {
+				if idx := strings.Index(col, "["); idx != -1 && looksLikeSlice(col[idx:]) {
+					col = col[:idx]
+				}
+				pc.excludes = append(pc.excludes, strings.TrimSpace(col[1:]))
+				continue
+			}
+			// If it has a sort prefix, it's for ordering, not for selection.
 			// In banquet, table/+id implies SELECT * FROM table ORDER BY id ASC.
 			if strings.HasPrefix(col, ASC) || strings.HasPrefix(col, DESC) {
+				// Strip any bracket suffix from the sort column
+				if idx := strings.Index(col, "["); idx != -1 {
+					col = col[:idx]
+				}
+				col, nulls := cutNulls(col)
+				if strings.HasPrefix(col, ASC) {
+					pc.sorts = append(pc.sorts, OrderTerm{Column: strings.TrimPrefix(col, ASC), Direction: "ASC", Nulls: nulls})
+				} else {
+					pc.sorts = append(pc.sorts, OrderTerm{Column: strings.TrimPrefix(col, DESC), Direction: "DESC", Nulls: nulls})
+				}
+				if !pc.sorts[len(pc.sorts)-1].IsPosition() {
+					pc.withSorts = append(pc.withSorts, pc.sorts[len(pc.sorts)-1].Column)
+				}
 				continue
 			}
 
-			collected = append(collected, col)
+			// Clean up slice notation from the column only if it looks like a slice
+			if idx := strings.Index(col, "["); idx != -1 {
+				if looksLikeSlice(col[idx:]) {
+					col = strings.TrimSpace(col[:idx])
+				}
+			}
+			if col == "" {
+				continue
+			}
+			pc.selects = append(pc.selects, col)
+			pc.withSorts = append(pc.withSorts, col)
 		}
 	}
+	return pc
+}
 
-	if len(collected) == 0 {
+// selectList returns the selected columns, defaulting to * when none were given.
+func (pc parsedColumns) selectList() []string {
+	if len(pc.selects) == 0 {
 		return []string{"*"}
 	}
+	return pc.selects
+}
 
-	return collected
+// where returns the path conditions rendered as SQL and joined with AND.
+func (pc parsedColumns) where() string {
+	conds := make([]string, len(pc.conditions))
+	for i, cond := range pc.conditions {
+		conds[i] = cond.String()
+	}
+	return strings.Join(conds, " AND ")
+}
+
+func ParseSelect(columnPath string) []string {
+	return scanColumnPath(columnPath).selectList()
 }
 
 func parsePathConditions(columnPath string) string {
-	segments := getSegments(columnPath)
-	if len(segments) == 0 {
+	return scanColumnPath(columnPath).where()
+}
+
+// OR separates alternative conditions within a single path token, e.g. status=active|status=pending.
+// Commas keep meaning AND between tokens.
+const OR = "|"
+
+// findOperator locates the leftmost comparison operator (!=, >=, <=, =, >, <, ~) in token.
+func findOperator(token string) (int, string) {
+	idx := strings.IndexAny(token, "!=<>~")
+	for idx != -1 {
+		rest := token[idx:]
+		for _, op := range []string{"!=", ">=", "<=", "=", ">", "<", CONTAINS} {
+			if strings.HasPrefix(rest, op) {
+				return idx, op
+			}
+		}
+		// a lone "!" is not an operator (e.g. the literal column !^col)
+		next := strings.IndexAny(token[idx+1:], "!=<>~")
+		if next == -1 {
+			break
+		}
+		idx += next + 1
+	}
+	return -1, ""
+}
+
+func hasOperator(token string) bool {
+	idx, _ := findOperator(token)
+	return idx != -1
+}
+
+// RANGE separates the bounds of a range condition, e.g. total=50..500.
+const RANGE = ".."
+
+func parseWhere(query string, strip, quoteLists bool) string {
+	where := rawQueryValues(query, "where")
+	if len(where) == 0 {
 		return ""
 	}
+	if strip {
+		where[0] = stripComments(where[0])
+	}
+	if quoteLists {
+		return QuoteInLists(where[0])
+	}
+	return where[0]
+}
 
-	var conditions []string
-	for _, segment := range segments {
-		if segment == "" {
+// inListNumber matches the IN list values QuoteInLists leaves bare.
+var inListNumber = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+// QuoteInLists single-quotes the bareword values of IN lists in a where expression, so
+// status in (active,pending) becomes status in ('active', 'pending'). Numbers and the
+// keywords TRUE, FALSE and NULL stay bare. Lists that already contain quotes or parentheses
+// (a subquery) are left as they are. Column names in a list are quoted too, which is why
+// ParseBanquet only calls it with ParseOptions.QuoteInLists.
+func QuoteInLists(where string) string {
+	var out strings.Builder
+	inString := false
+	for i := 0; i < len(where); i++ {
+		c := where[i]
+		if c == '\'' {
+			inString = !inString
+		}
+		if inString || c != 'i' && c != 'I' || i > 0 && isIdentChar(where[i-1]) ||
+			!strings.EqualFold(where[i:min(i+2, len(where))], "in") {
+			out.WriteByte(c)
 			continue
 		}
-		// Conditions can be comma separated? e.g. col1!=val1,col2!=val2
-		// Assuming yes since ParseSelect splits by comma.
-		parts := strings.Split(segment, ",")
-		for _, part := range parts {
-			if strings.Contains(part, "!=") {
-				// Split
-				kv := strings.SplitN(part, "!=", 2)
-				if len(kv) == 2 {
-					col := strings.TrimSpace(kv[0])
-					val := strings.TrimSpace(kv[1])
-
-					// URL Decode value
-					decodedVal, err := url.QueryUnescape(val)
-					if err == nil {
-						val = decodedVal
-					}
-
-					// Quote if not number
-					if _, err := strconv.ParseFloat(val, 64); err != nil {
-						// Quote single quotes for SQL safety
-						val = strings.ReplaceAll(val, "'", "''")
-						val = "'" + val + "'"
-					}
-
-					conditions = append(conditions, fmt.Sprintf("%s != %s", col, val))
-				}
+		open := i + 2
+		for open < len(where) && where[open] == ' ' {
+			open++
+		}
+		if open == i+2 && open < len(where) && isIdentChar(where[open]) || open >= len(where) || where[open] != '(' {
+			out.WriteByte(c)
+			continue
+		}
+		end := strings.IndexByte(where[open:], ')')
+		if end == -1 {
+			out.WriteByte(c)
+			continue
+		}
+		end += open
+		list := where[open+1 : end]
+		if first, _, _ := strings.Cut(strings.TrimSpace(list), " "); first == "" || strings.EqualFold(first, "select") ||
+			strings.ContainsAny(list, "'\"(") {
+			out.WriteByte(c)
+			continue
+		}
+		values := strings.Split(list, ",")
+		for j, v := range values {
+			v = strings.TrimSpace(v)
+			if !inListNumber.MatchString(v) && !isLiteralKeyword(v) {
+				v = "'" + v + "'"
 			}
+			values[j] = v
 		}
+		out.WriteString(where[i:open] + "(" + strings.Join(values, ", ") + ")")
+		i = end
 	}
+	return out.String()
+}
 
-	if len(conditions) == 0 {
-		return ""
-	}
-	return strings.Join(conditions, " AND ")
+// isLiteralKeyword reports whether v is TRUE, FALSE or NULL in any case.
+func isLiteralKeyword(v string) bool {
+	return strings.EqualFold(v, "true") || strings.EqualFold(v, "false") || strings.EqualFold(v, "null")
 }
 
-func parseWhere(query string) string {
-	if query == "" {
-		return ""
+// rawQueryValues returns every value of key in the raw query. Values are %-decoded only,
+// so a + stays a + (arithmetic in SQL) instead of becoming a space as in form decoding.
+// url.ParseQuery is too strict for Banquet's "unescape tolerant" goal, so a value that fails
+// to decode is returned raw.
+func rawQueryValues(query, key string) []string {
+	var values []string
+	for _, p := range strings.Split(query, "&") {
+		k, val, _ := strings.Cut(p, "=")
+		if decoded, err := url.PathUnescape(k); err == nil {
+			k = decoded
+		}
+		if k != key {
+			continue
+		}
+		if decoded, err := url.PathUnescape(val); err == nil {
+			val = decoded
+		}
+		values = append(values, val)
 	}
-	// Simple extraction of 'where' parameter
-	// url.ParseQuery is too strict for Banquet's "unescape tolerant" goal.
-	params := strings.Split(query, "&")
-	for _, p := range params {
-		if strings.HasPrefix(p, "where=") {
-			val := strings.TrimPrefix(p, "where=")
-			// Try to unescape but if it fails, just return the raw value
-			if decoded, err := url.QueryUnescape(val); err == nil {
-				return decoded
-			}
-			return val
+	return values
+}
+
+// queryParams are the query params Banquet or its handler interpret. Others go to Banquet.Extra.
+var queryParams = map[string]bool{
+	"where": true, "having": true, "groupby": true, "orderby": true, "select": true, "select_sort": true,
+	"table": true, "limit": true, "offset": true, "top": true, "distinct": true, "rawsql": true, "format": true,
+}
+
+// extraParams returns the params of the raw query not in queryParams, in order. They are
+// decoded as a form would be, and a key or value that fails to decode is kept raw.
+func extraParams(query string) []QueryParam {
+	var extra []QueryParam
+	for _, p := range strings.Split(query, "&") {
+		if p == "" {
+			continue
+		}
+		k, val, _ := strings.Cut(p, "=")
+		if decoded, err := url.QueryUnescape(k); err == nil {
+			k = decoded
+		}
+		if queryParams[k] {
+			continue
+		}
+		if decoded, err := url.QueryUnescape(val); err == nil {
+			val = decoded
 		}
+		extra = append(extra, QueryParam{Key: k, Value: val})
 	}
-	return ""
+	return extra
 }
 
 func ParseGroupBy(path string, query string) string {
-	// check query first
 	v, _ := url.ParseQuery(query)
+	return parseGroupBy(path, v)
+}
+
+// parseGroupBy is ParseGroupBy over already parsed query values.
+func parseGroupBy(path string, v url.Values) string {
+	// check query first
 	if g := v.Get("groupby"); g != "" {
 		return g
 	}
 
-	// check path for (expression)
-	if strings.Contains(path, "(") && strings.Contains(path, ")") {
-		start := strings.Index(path, "(")
-		end := strings.Index(path, ")")
-		if start < end {
+	// check path for (expression), skipping calls to ScalarFunctions such as upper(name)
+	// and tuple IN conditions such as (region,product)=[(west,widget)]
+	for offset := 0; ; {
+		start := strings.Index(path[offset:], "(")
+		end := strings.Index(path[offset:], ")")
+		if start == -1 || end == -1 || start > end {
+			return ""
+		}
+		start, end = offset+start, offset+end
+		if strings.HasPrefix(path[end+1:], "=[") {
+			close := strings.Index(path[end:], "]")
+			if close == -1 {
+				return ""
+			}
+			offset = end + close + 1
+			continue
+		}
+		name := start
+		for name > 0 && isIdentChar(path[name-1]) {
+			name--
+		}
+		if !ScalarFunctions[strings.ToLower(path[name:start])] {
 			return path[start+1 : end]
 		}
+		offset = end + 1
 	}
-	return ""
 }
 
 // parseTable attempts to identify the table from the path.
@@ -473,7 +1319,8 @@ func parseTable(columnPath string) string {
 		strings.Contains(first, "=") ||
 		strings.Contains(first, ">") ||
 		strings.Contains(first, "<") ||
-		(strings.HasPrefix(first, "[") && strings.Contains(first, ":")) {
+		strings.Contains(first, CONTAINS) ||
+		(strings.HasPrefix(first, "[") && looksLikeSlice(first)) {
 		return ""
 	}
 
@@ -484,8 +1331,7 @@ func parseTable(columnPath string) string {
 	return first
 }
 
-func parseLimit(query string, path string) string {
-	v, _ := url.ParseQuery(query)
+func parseLimit(v url.Values, path string) string {
 	if l := v.Get("limit"); l != "" {
 		return l
 	}
@@ -496,8 +1342,7 @@ func parseLimit(query string, path string) string {
 	return ""
 }
 
-func parseOffset(query string, path string) string {
-	v, _ := url.ParseQuery(query)
+func parseOffset(v url.Values, path string) string {
 	if o := v.Get("offset"); o != "" {
 		return o
 	}
@@ -505,81 +1350,234 @@ func parseOffset(query string, path string) string {
 	return offset
 }
 
-func parseHaving(query string) string {
-	v, _ := url.ParseQuery(query)
-	return v.Get("having")
+// parseHaving combines every having param with AND, e.g. having=count(*)>5&having=sum(total)>100.
+func parseHaving(query string, strip bool) string {
+	having := rawQueryValues(query, "having")
+	var conds []string
+	for _, h := range having {
+		if strip {
+			h = stripComments(h)
+		}
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if cond, ok := structuredHaving(h); ok {
+			h = cond
+		} else if len(having) > 1 && strings.Contains(strings.ToUpper(h), " OR ") {
+			// Keep OR groups intact when ANDed with other conditions
+			h = "(" + h + ")"
+		}
+		conds = append(conds, h)
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// havingCondition matches the structured having form: an aggregate call or a bare count,
+// a comparison operator and a plain value, e.g. count>5 or sum(total)>=100.
+var havingCondition = regexp.MustCompile(`^(count|\w+\(\s*(?:\w+|\*)\s*\))\s*(=|!=|<>|<=|>=|<|>)\s*([^\s'"()\\]+)$`)
+
+// structuredHaving renders a having param in the structured form like a path condition:
+// count alone means count(*) and the value is typed, so count>5 becomes count(*) > 5 and
+// max(status)=done becomes max(status) = 'done'. As in the path, a column is compared with a
+// leading @: sum(total)>@budget becomes sum(total) > budget. Other having params are raw SQL.
+func structuredHaving(h string) (string, bool) {
+	m := havingCondition.FindStringSubmatch(h)
+	if m == nil {
+		return "", false
+	}
+	agg := m[1]
+	if strings.EqualFold(agg, "count") {
+		agg = "count(*)"
+	}
+	if ref, ok := havingColumn(m[3]); ok {
+		return agg + " " + m[2] + " " + ref, true
+	}
+	return agg + " " + m[2] + " " + literal(m[3], isNumeric(m[3])), true
+}
+
+// havingColumn returns the column of a structured having value written @col.
+func havingColumn(val string) (string, bool) {
+	ref, ok := strings.CutPrefix(val, "@")
+	return ref, ok && isBareIdentifier(ref)
+}
+
+// QuoteAggregates quotes the bare column argument of function calls in expr,
+// e.g. sum(total)>100 becomes sum("total")>100 with a double-quote quoter.
+// Function names, * and anything inside single-quoted literals are left untouched.
+func QuoteAggregates(expr string, quote func(string) string) string {
+	var out strings.Builder
+	inString := false
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if c == '\'' {
+			inString = !inString
+		}
+		if c != '(' || inString || i == 0 || !isIdentChar(expr[i-1]) {
+			out.WriteByte(c)
+			continue
+		}
+		end := strings.IndexByte(expr[i:], ')')
+		if end == -1 {
+			out.WriteByte(c)
+			continue
+		}
+		arg := strings.TrimSpace(expr[i+1 : i+end])
+		if !isBareIdentifier(arg) {
+			out.WriteByte(c)
+			continue
+		}
+		out.WriteString("(" + quote(arg) + ")")
+		i += end
+	}
+	return out.String()
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// isBareIdentifier reports whether s is a plain unquoted column name.
+func isBareIdentifier(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isIdentChar(s[i]) {
+			return false
+		}
+	}
+	return true
 }
 
 func parseOrderBy(columnPath string, query string) (string, string) {
 	v, _ := url.ParseQuery(query)
-	if ob := v.Get("orderby"); ob != "" {
-		return ob, ""
+	sorts := parseSorts(scanColumnPath(columnPath), v)
+	if len(sorts) == 0 {
+		return "", ""
 	}
+	return sorts[0].Column, sorts[0].Direction
+}
 
-	// check path parts
-	parts := strings.Split(columnPath, "/")
-	for _, part := range parts {
-		cols := strings.Split(part, ",")
-		for _, col := range cols {
-			col = strings.TrimSpace(col)
-			// Strip slice notation
-			if idx := strings.Index(col, "["); idx != -1 {
-				col = col[:idx]
-			}
-			if strings.HasPrefix(col, ASC) {
-				return strings.TrimPrefix(col, ASC), "ASC"
+// dedupe returns cols without repeats, keeping the first occurrence of each.
+func dedupe(cols []string) []string {
+	seen := make(map[string]bool, len(cols))
+	var out []string
+	for _, col := range cols {
+		if !seen[col] {
+			seen[col] = true
+			out = append(out, col)
+		}
+	}
+	return out
+}
+
+// cutNulls removes a trailing :nullsfirst or :nullslast from a sort term and returns
+// the OrderTerm.Nulls it stands for.
+func cutNulls(term string) (string, string) {
+	idx := strings.LastIndex(term, ":")
+	if idx == -1 {
+		return term, ""
+	}
+	switch strings.ToLower(term[idx+1:]) {
+	case "nullsfirst":
+		return term[:idx], "FIRST"
+	case "nullslast":
+		return term[:idx], "LAST"
+	}
+	return term, ""
+}
+
+// parseSorts collects ORDER BY terms from the orderby query param, falling back to +/- prefixed path columns.
+// The query param accepts name, name:desc, -name and comma separated lists like lastname:asc,firstname:desc.
+// Any term may end in :nullsfirst or :nullslast, e.g. name:desc:nullslast.
+func parseSorts(cols parsedColumns, v url.Values) []OrderTerm {
+	if ob := v.Get("orderby"); ob != "" {
+		var sorts []OrderTerm
+		for _, term := range splitColumns(ob) {
+			// "+name" arrives as " name" after query decoding, so trimming also drops an ASC prefix
+			term = strings.TrimSpace(term)
+			if term == "" {
+				continue
 			}
-			if strings.HasPrefix(col, DESC) {
-				return strings.TrimPrefix(col, DESC), "DESC"
+			term, nulls := cutNulls(term)
+			sort := OrderTerm{Column: term}
+			if strings.HasPrefix(term, ASC) {
+				sort = OrderTerm{Column: strings.TrimPrefix(term, ASC), Direction: "ASC"}
+			} else if strings.HasPrefix(term, DESC) {
+				sort = OrderTerm{Column: strings.TrimPrefix(term, DESC), Direction: "DESC"}
+			} else if idx := strings.LastIndex(term, ":"); idx != -1 {
+				switch strings.ToUpper(term[idx+1:]) {
+				case "ASC", "DESC":
+					sort = OrderTerm{Column: term[:idx], Direction: strings.ToUpper(term[idx+1:])}
+				}
 			}
+			sort.Nulls = nulls
+			sorts = append(sorts, sort)
 		}
+		return sorts
 	}
-	return "", ""
+
+	return cols.sorts
 }
 
-func parseSlice(pathStr string) (string, string) {
-	// Relaxed to find slice notation anywhere in the string
-	startIdx := strings.LastIndex(pathStr, "[")
-	if startIdx == -1 {
-		return "", ""
+// sortPosition matches a positional sort column: a 1-based index into the select list.
+var sortPosition = regexp.MustCompile(`^[1-9][0-9]*$`)
+
+// IsPosition reports whether t orders by a select list position, e.g. 2 for the second column.
+func (t OrderTerm) IsPosition() bool {
+	return sortPosition.MatchString(t.Column)
+}
+
+// checkPositions drops positional sorts past the end of selects and returns a *ValidationError
+// for the first. They are only checked when selects lists its columns; with a * (or exclusions
+// from one) the count isn't known until the table is.
+func checkPositions(sorts []OrderTerm, selects, excludes []string) ([]OrderTerm, error) {
+	if len(excludes) > 0 || len(selects) == 0 || slices.Contains(selects, "*") {
+		return sorts, nil
 	}
-	endIdx := strings.Index(pathStr[startIdx:], "]")
-	if endIdx == -1 {
-		return "", ""
+	var err error
+	kept := sorts[:0:0]
+	for _, sort := range sorts {
+		if n, _ := strconv.Atoi(sort.Column); sort.IsPosition() && n > len(selects) {
+			if err == nil {
+				err = &ValidationError{Field: "OrderBy", Value: sort.Column, Reason: fmt.Sprintf("position past the %d selected columns", len(selects))}
+			}
+			continue
+		}
+		kept = append(kept, sort)
 	}
-	// absolute end index
-	endIdx += startIdx
+	if err == nil {
+		return sorts, nil
+	}
+	return kept, err
+}
 
-	content := pathStr[startIdx+1 : endIdx]
-	parts := strings.Split(content, ":")
-	if len(parts) != 2 {
+func parseSlice(pathStr string) (string, string) {
+	startStr, endStr, ok := sliceBounds(pathStr)
+	if !ok {
 		return "", ""
 	}
 
-	startStr := strings.TrimSpace(parts[0])
-	endStr := strings.TrimSpace(parts[1])
-
 	start := 0
 	end := 0
 	hasLimit := false
 
 	if startStr != "" {
-		s, err := strconv.Atoi(startStr)
-		if err != nil {
-			return "", ""
-		}
-		start = s
+		start, _ = strconv.Atoi(startStr)
 	}
 
 	if endStr != "" {
-		e, err := strconv.Atoi(endStr)
-		if err != nil {
-			return "", ""
-		}
-		end = e
+		end, _ = strconv.Atoi(endStr)
 		hasLimit = true
 	}
 
+	// Negative indices count from the end and can't become LIMIT/OFFSET without a row count.
+	// ParseBanquet records them via FromEnd instead.
+	if start < 0 || end < 0 {
+		return "", ""
+	}
+
 	offset := start
 	limit := ""
 
@@ -593,3 +1591,155 @@ func parseSlice(pathStr string) (string, string) {
 
 	return limit, strconv.Itoa(offset)
 }
+
+// bareSlice matches a final N:M column path token written without brackets, e.g. users;0:10.
+// Both bounds must be integers so aliases such as col:name are never taken for a slice.
+var bareSlice = regexp.MustCompile(`(^|[/,])(-?\d+:-?\d+)$`)
+
+// cutBareSlice removes a trailing bare N:M token from columnPath and returns it bracketed.
+func cutBareSlice(columnPath string) (string, string) {
+	m := bareSlice.FindStringSubmatchIndex(columnPath)
+	if m == nil {
+		return columnPath, ""
+	}
+	return columnPath[:m[2]], "[" + columnPath[m[4]:m[5]] + "]"
+}
+
+// outputFormat matches an output format suffix on the last tier.
+var outputFormat = regexp.MustCompile(`(?i)\.(csv|json)$`)
+
+// cutOutputFormat removes a trailing .csv or .json from tier and returns the format in lower case.
+// The suffix must follow a name, and in the column tier a column rather than a condition value,
+// so users;file=a.csv still filters on a.csv.
+func cutOutputFormat(tier string) (string, string) {
+	loc := outputFormat.FindStringIndex(tier)
+	if loc == nil {
+		return tier, ""
+	}
+	last := tier[strings.LastIndexAny(tier[:loc[0]], ",/")+1 : loc[0]]
+	if strings.TrimSpace(last) == "" || hasOperator(last) {
+		return tier, ""
+	}
+	return tier[:loc[0]], strings.ToLower(tier[loc[0]+1:])
+}
+
+// isNegative reports whether the slice bound s is a negative integer; -0 is not.
+func isNegative(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n < 0
+}
+
+// looksLikeSlice reports whether s (starting at a "[") holds slice notation:
+// either a colon ([10:20]) or a single integer limit ([10]).
+func looksLikeSlice(s string) bool {
+	if strings.Contains(s, ":") {
+		return true
+	}
+	end := strings.Index(s, "]")
+	if !strings.HasPrefix(s, "[") || end == -1 {
+		return false
+	}
+	_, err := strconv.Atoi(strings.TrimSpace(s[1:end]))
+	return err == nil
+}
+
+// sliceBounds extracts the raw start and end of the last [start:end] in pathStr.
+// Each bound is either empty or an integer; ok is false when no well-formed slice is found.
+func sliceBounds(pathStr string) (startStr string, endStr string, ok bool) {
+	startStr, endStr, found, err := parseSliceBounds(pathStr)
+	return startStr, endStr, found && err == nil
+}
+
+// parseSliceBounds is the error-returning variant behind sliceBounds.
+// Brackets that don't look like a slice (e.g. [^]) are not an error; brackets that do but are
+// malformed ([abc:10], [10], [1:2:3:4]) return a *ValidationError for the Slice field.
+func parseSliceBounds(pathStr string) (startStr string, endStr string, found bool, err error) {
+	// Relaxed to find slice notation anywhere in the string
+	startIdx := strings.LastIndex(pathStr, "[")
+	if startIdx == -1 {
+		return "", "", false, nil
+	}
+	endIdx := strings.Index(pathStr[startIdx:], "]")
+	if endIdx == -1 {
+		return "", "", false, nil
+	}
+	// absolute end index
+	endIdx += startIdx
+
+	content := pathStr[startIdx+1 : endIdx]
+	parts := strings.Split(content, ":")
+	if len(parts) == 1 {
+		// [N] is shorthand for [0:N]
+		n, err := strconv.Atoi(strings.TrimSpace(content))
+		if err != nil {
+			// Not slice notation at all
+			return "", "", false, nil
+		}
+		if n < 0 {
+			return "", "", true, &ValidationError{Field: "Slice", Value: pathStr[startIdx : endIdx+1], Reason: "negative single value slice"}
+		}
+		return "", strconv.Itoa(n), true, nil
+	}
+	if len(parts) != 2 {
+		return "", "", true, &ValidationError{Field: "Slice", Value: pathStr[startIdx : endIdx+1], Reason: "too many colons"}
+	}
+
+	startStr = strings.TrimSpace(parts[0])
+	endStr = strings.TrimSpace(parts[1])
+	for _, bound := range []string{startStr, endStr} {
+		if bound == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(bound); err != nil {
+			return "", "", true, &ValidationError{Field: "Slice", Value: pathStr[startIdx : endIdx+1], Reason: "bound is not an integer"}
+		}
+	}
+	return startStr, endStr, true, nil
+}
+
+// ResolveFromEnd translates a slice with negative indices (FromEnd) into Limit and Offset,
+// given the total number of rows. Indices follow Python semantics: [-10:] is the last 10 rows
+// and [:-5] is everything but the last 5. A Limit already set, e.g. by ParseOptions.MaxLimit,
+// caps the resolved one. It does nothing when FromEnd is false.
+func (b *Banquet) ResolveFromEnd(rowCount int) {
+	if !b.FromEnd {
+		return
+	}
+	resolve := func(bound string, def int) int {
+		if bound == "" {
+			return def
+		}
+		n, _ := strconv.Atoi(bound)
+		if n < 0 {
+			n += rowCount
+		}
+		return max(0, min(n, rowCount))
+	}
+	start := resolve(b.sliceStart, 0)
+	end := resolve(b.sliceEnd, rowCount)
+	limit := max(0, end-start)
+	if n, err := strconv.Atoi(b.Limit); err == nil && n >= 0 {
+		limit = min(limit, n)
+	}
+	b.Offset = strconv.Itoa(start)
+	b.Limit = strconv.Itoa(limit)
+	b.FromEnd = false
+}
+
+// LimitInt returns Limit as an int, or def when it is empty, not an integer or negative.
+func (b *Banquet) LimitInt(def int) int {
+	return atoiOr(b.Limit, def)
+}
+
+// OffsetInt returns Offset as an int, or def when it is empty, not an integer or negative.
+func (b *Banquet) OffsetInt(def int) int {
+	return atoiOr(b.Offset, def)
+}
+
+func atoiOr(s string, def int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
@@ -1,7 +1,13 @@
 package banquet
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -51,14 +57,21 @@ func TestLocalParse(t *testing.T) {
 		t.Errorf("DataSetPath mismatch: got %q, want %q", b.DataSetPath, "some/local/path/file.csv")
 	}
 
-	// 2. Verify Table identification (in 2-tier format, second part is Table)
-	if b.Table != "col1,col2,col3" {
-		t.Errorf("Table mismatch: got %q, want %q", b.Table, "col1,col2,col3")
+	// 2. Verify Table identification (flat file in 2-tier format, second part is columns, not a table)
+	if b.Table != "" {
+		t.Errorf("Table mismatch: got %q, want %q", b.Table, "")
 	}
 
-	// 3. Verify Selection (no column tier provided, so should default to *)
-	if len(b.Select) != 1 || b.Select[0] != "*" {
-		t.Errorf("Select mismatch: got %v, want %v", b.Select, []string{"*"})
+	// 3. Verify Selection (second tier of a flat file is the column list)
+	expectedSelect := []string{"col1", "col2", "col3"}
+	if len(b.Select) != len(expectedSelect) {
+		t.Errorf("Select mismatch: got %v, want %v", b.Select, expectedSelect)
+	} else {
+		for i, col := range expectedSelect {
+			if b.Select[i] != col {
+				t.Errorf("Select[%d] mismatch: got %q, want %q", i, b.Select[i], col)
+			}
+		}
 	}
 
 	// 4. Add a more complex case to be truly thorough
@@ -190,8 +203,8 @@ func TestParseBanquet(t *testing.T) {
 	if b.GroupBy != "department" {
 		t.Errorf("Expected GroupBy 'department', got '%s'", b.GroupBy)
 	}
-	if b.Having != "count>1" {
-		t.Errorf("Expected Having 'count>1', got '%s'", b.Having)
+	if b.Having != "count(*) > 1" {
+		t.Errorf("Expected Having 'count(*) > 1', got '%s'", b.Having)
 	}
 
 }
@@ -261,6 +274,23 @@ func TestParseSelect(t *testing.T) {
 	}
 }
 
+func TestParseSelectEscapedComma(t *testing.T) {
+	want := []string{"last, first", "id"}
+	if got := ParseSelect(`last\, first,id,+age`); !slices.Equal(got, want) {
+		t.Errorf("ParseSelect() = %q, want %q", got, want)
+	}
+	b, err := ParseBanquet("data.sqlite;users;last%2C%20first,id")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	if !slices.Equal(b.Select, want) {
+		t.Errorf("Select = %q, want %q", b.Select, want)
+	}
+	if got, wantPath := b.ColumnPathCanonical(), `last\, first,id`; got != wantPath {
+		t.Errorf("ColumnPathCanonical() = %q, want %q", got, wantPath)
+	}
+}
+
 func TestParseGroupBy(t *testing.T) {
 	TestLog(t)
 	afterPart := "some_column(group_column)"
@@ -295,3 +325,1152 @@ func TestParseLegacyLiteral(t *testing.T) {
 		t.Errorf("Expected no OrderBy for legacy literals, got %s (%s)", ob, dir)
 	}
 }
+
+func TestFlatFileTwoTier(t *testing.T) {
+	// A single column in the 2-tier form must not be mistaken for a table
+	b, err := ParseBanquet("users.csv;name")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	if b.Table != "" {
+		t.Errorf("Expected empty Table, got %q", b.Table)
+	}
+	if len(b.Select) != 1 || b.Select[0] != "name" {
+		t.Errorf("Expected Select [name], got %v", b.Select)
+	}
+
+	// Container datasets keep the second tier as the table
+	b, err = ParseBanquet("data.sqlite;users")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	if b.Table != "users" {
+		t.Errorf("Expected Table 'users', got %q", b.Table)
+	}
+}
+
+func TestOrderByQueryDirection(t *testing.T) {
+	tests := []struct {
+		url   string
+		sorts []OrderTerm
+	}{
+		{"data.sqlite;users?orderby=name", []OrderTerm{{Column: "name", Direction: ""}}},
+		{"data.sqlite;users?orderby=name:desc", []OrderTerm{{Column: "name", Direction: "DESC"}}},
+		{"data.sqlite;users?orderby=-name", []OrderTerm{{Column: "name", Direction: "DESC"}}},
+		{"data.sqlite;users?orderby=%2Bname", []OrderTerm{{Column: "name", Direction: "ASC"}}},
+		{"data.sqlite;users?orderby=lastname:asc,firstname:desc", []OrderTerm{{Column: "lastname", Direction: "ASC"}, {Column: "firstname", Direction: "DESC"}}},
+		{"data.sqlite;users;+lastname,-firstname", []OrderTerm{{Column: "lastname", Direction: "ASC"}, {Column: "firstname", Direction: "DESC"}}},
+		{"data.sqlite;users;+lastname:nullsfirst,-age:nullslast", []OrderTerm{{Column: "lastname", Direction: "ASC", Nulls: "FIRST"}, {Column: "age", Direction: "DESC", Nulls: "LAST"}}},
+		{"data.sqlite;users?orderby=name:desc:NULLSLAST,id:nullsfirst", []OrderTerm{{Column: "name", Direction: "DESC", Nulls: "LAST"}, {Column: "id", Nulls: "FIRST"}}},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if len(b.Sorts) != len(tt.sorts) {
+			t.Errorf("%s: expected Sorts %v, got %v", tt.url, tt.sorts, b.Sorts)
+			continue
+		}
+		for i := range tt.sorts {
+			if b.Sorts[i] != tt.sorts[i] {
+				t.Errorf("%s: Sorts[%d] = %v, want %v", tt.url, i, b.Sorts[i], tt.sorts[i])
+			}
+		}
+		if b.OrderBy != tt.sorts[0].Column || b.SortDirection != tt.sorts[0].Direction {
+			t.Errorf("%s: OrderBy/SortDirection = %q/%q, want %q/%q", tt.url, b.OrderBy, b.SortDirection, tt.sorts[0].Column, tt.sorts[0].Direction)
+		}
+	}
+}
+
+func TestParseAuth(t *testing.T) {
+	tests := []struct {
+		url  string
+		auth Auth
+	}{
+		{"gs://matrix@bucket/file.csv", Auth{Username: "matrix", Token: "matrix"}},
+		{"gs://user:pass@bucket/file.csv", Auth{Username: "user", Password: "pass"}},
+		{"gs://eyJhbGciOiJIUzI1NiJ9.e30.sig@bucket/file.csv", Auth{Username: "eyJhbGciOiJIUzI1NiJ9.e30.sig", Token: "eyJhbGciOiJIUzI1NiJ9.e30.sig"}},
+		{"gs://bucket/file.csv", Auth{}},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if b.Auth != tt.auth {
+			t.Errorf("%s: Auth = %+v, want %+v", tt.url, b.Auth, tt.auth)
+		}
+	}
+}
+
+func TestRegisterExtension(t *testing.T) {
+	defer SetExtensions(nil)
+
+	// Unknown extensions don't split until registered
+	b, err := ParseBanquet("data/events.ndjson/id,name")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	if b.DataSetPath != "data/events.ndjson/id,name" {
+		t.Errorf("Expected unsplit DataSetPath, got %q", b.DataSetPath)
+	}
+
+	RegisterExtension("NDJSON")
+	b, err = ParseBanquet("data/events.ndjson/id,name")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	if b.DataSetPath != "data/events.ndjson" {
+		t.Errorf("Expected DataSetPath 'data/events.ndjson', got %q", b.DataSetPath)
+	}
+	if b.ColumnPath != "id,name" {
+		t.Errorf("Expected ColumnPath 'id,name', got %q", b.ColumnPath)
+	}
+}
+
+func TestDefaultExtensions(t *testing.T) {
+	defer SetExtensions(nil)
+
+	RegisterExtension(".feather")
+	SetExtensions(nil)
+	got := Extensions()
+	if len(got) != len(defaultExtensions) {
+		t.Fatalf("Expected default extensions %v, got %v", defaultExtensions, got)
+	}
+	for i := range got {
+		if got[i] != defaultExtensions[i] {
+			t.Errorf("Extensions()[%d] = %q, want %q", i, got[i], defaultExtensions[i])
+		}
+	}
+
+	b, err := ParseBanquet("data/sales.csv/amount")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	if b.DataSetPath != "data/sales.csv" || b.ColumnPath != "amount" {
+		t.Errorf("Expected split data/sales.csv + amount, got %q + %q", b.DataSetPath, b.ColumnPath)
+	}
+}
+
+func TestCaseInsensitiveExtensions(t *testing.T) {
+	tests := []struct {
+		url         string
+		dataSetPath string
+		columnPath  string
+	}{
+		{"reports/DATA.CSV/id,name", "reports/DATA.CSV", "id,name"},
+		{"reports/Report.XLSX/Sheet1", "reports/Report.XLSX", "Sheet1"},
+		{"History.Db/raw_content", "History.Db", "raw_content"},
+		{"Users.Csv;id,name", "Users.Csv", "id,name"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if b.DataSetPath != tt.dataSetPath {
+			t.Errorf("%s: DataSetPath = %q, want %q", tt.url, b.DataSetPath, tt.dataSetPath)
+		}
+		if b.ColumnPath != tt.columnPath {
+			t.Errorf("%s: ColumnPath = %q, want %q", tt.url, b.ColumnPath, tt.columnPath)
+		}
+	}
+}
+
+func TestParseBanquetContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b, err := ParseBanquetContext(ctx, "data.sqlite;users;id,name")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if b != nil {
+		t.Errorf("Expected nil Banquet on cancellation, got %+v", b)
+	}
+
+	b, err = ParseBanquetContext(context.Background(), "data.sqlite;users;id,name")
+	if err != nil || b.Table != "users" {
+		t.Errorf("Expected successful parse, got %v, %v", b, err)
+	}
+}
+
+func BenchmarkParseBanquet(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		url  string
+	}{
+		{"Simple", "data.sqlite;users"},
+		{"Complex", "gs://matrix@bucket.appspot.com:8080/data.sqlite;users;id,name,-age,status!=active[10:20]?where=age>18&groupby=country&having=count(*)>5&orderby=name:desc"},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseBanquet(bm.url); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestScanColumnPathCorpus pins the single-pass scanner to the results the separate
+// ParseSelect/parsePathConditions/parseOrderBy walks produced before the refactor.
+func TestScanColumnPathCorpus(t *testing.T) {
+	tests := []struct {
+		columnPath string
+		selects    []string
+		where      string
+		sorts      []OrderTerm
+	}{
+		{"", []string{"*"}, "", nil},
+		{"users", []string{"users"}, "", nil},
+		{"col1,col2,col3", []string{"col1", "col2", "col3"}, "", nil},
+		{"*", []string{"*"}, "", nil},
+		{"+name", []string{"*"}, "", []OrderTerm{{Column: "name", Direction: "ASC"}}},
+		{"-created_at", []string{"*"}, "", []OrderTerm{{Column: "created_at", Direction: "DESC"}}},
+		{"id,+name", []string{"id"}, "", []OrderTerm{{Column: "name", Direction: "ASC"}}},
+		{"id,-age,email", []string{"id", "email"}, "", []OrderTerm{{Column: "age", Direction: "DESC"}}},
+		{"id[5:15],name", []string{"id", "name"}, "", nil},
+		{"id,name[0:50]", []string{"id", "name"}, "", nil},
+		{"status!=active", []string{"*"}, `"status" != 'active'`, nil},
+		{"status!=active,role!=admin", []string{"*"}, `"status" != 'active' AND "role" != 'admin'`, nil},
+		{"id,email,+joined[10:20]", []string{"id", "email"}, "", []OrderTerm{{Column: "joined", Direction: "ASC"}}},
+		{"name!=O%27Reilly", []string{"*"}, `"name" != 'O''Reilly'`, nil},
+		{"mytable/col1", []string{"col1"}, "", nil},
+		{"column1,+column2,-column3", []string{"column1"}, "", []OrderTerm{{Column: "column2", Direction: "ASC"}, {Column: "column3", Direction: "DESC"}}},
+		{"^column1,!^column2", []string{"^column1", "!^column2"}, "", nil},
+		{"column1,column2/+column3", []string{"column1", "column2"}, "", []OrderTerm{{Column: "column3", Direction: "ASC"}}},
+		{"raw_content/academic_resume_cv!=Undergraduate%20Studies", []string{"*"}, `"academic_resume_cv" != 'Undergraduate Studies'`, nil},
+		{"users/+lastname", []string{"*"}, "", []OrderTerm{{Column: "lastname", Direction: "ASC"}}},
+		{"users/status!=active", []string{"*"}, `"status" != 'active'`, nil},
+		{"users[10:20]", []string{"users"}, "", nil},
+		{"id, name ,  +age", []string{"id", "name"}, "", []OrderTerm{{Column: "age", Direction: "ASC"}}},
+		{"a,,b", []string{"a", "b"}, "", nil},
+		{"users/id,name/+age,-score/x!=5", []string{"id", "name"}, `"x" != 5`, []OrderTerm{{Column: "age", Direction: "ASC"}, {Column: "score", Direction: "DESC"}}},
+	}
+	for _, tt := range tests {
+		selects := ParseSelect(tt.columnPath)
+		if fmt.Sprint(selects) != fmt.Sprint(tt.selects) {
+			t.Errorf("ParseSelect(%q) = %v, want %v", tt.columnPath, selects, tt.selects)
+		}
+		if where := parsePathConditions(tt.columnPath); where != tt.where {
+			t.Errorf("parsePathConditions(%q) = %q, want %q", tt.columnPath, where, tt.where)
+		}
+		sorts := scanColumnPath(tt.columnPath).sorts
+		if fmt.Sprint(sorts) != fmt.Sprint(tt.sorts) {
+			t.Errorf("scanColumnPath(%q).sorts = %v, want %v", tt.columnPath, sorts, tt.sorts)
+		}
+		ob, dir := parseOrderBy(tt.columnPath, "")
+		if len(tt.sorts) > 0 && (ob != tt.sorts[0].Column || dir != tt.sorts[0].Direction) {
+			t.Errorf("parseOrderBy(%q) = %q, %q, want %v", tt.columnPath, ob, dir, tt.sorts[0])
+		}
+	}
+}
+
+func TestParseHavingMultiple(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;orders?having=count(*)>5&having=sum(total)>100%20OR%20avg(total)>10")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	want := "count(*) > 5 AND (sum(total)>100 OR avg(total)>10)"
+	if b.Having != want {
+		t.Errorf("Expected Having %q, got %q", want, b.Having)
+	}
+}
+
+func TestWhereHavingPercentDecoding(t *testing.T) {
+	tests := []struct{ url, where, having string }{
+		{"data.sqlite;orders?where=a+b=1", "a+b=1", ""},
+		{"data.sqlite;orders?where=a%20b=1", "a b=1", ""},
+		{"data.sqlite;orders?where=price%2Btax>100%20AND%20qty>1", "price+tax>100 AND qty>1", ""},
+		{"data.sqlite;orders?having=sum(a+b)>10", "", "sum(a+b)>10"},
+		{"data.sqlite;orders?having=count(*)%20>%205", "", "count(*) > 5"},
+		// Structured values are quoted unless written @col
+		{"data.sqlite;orders?having=max(status)=done", "", "max(status) = 'done'"},
+		{"data.sqlite;orders?having=sum(total)>@budget", "", "sum(total) > budget"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if b.Where != tt.where || b.Having != tt.having {
+			t.Errorf("%s: Where/Having = %q/%q, want %q/%q", tt.url, b.Where, b.Having, tt.where, tt.having)
+		}
+	}
+}
+
+func TestQuoteAggregates(t *testing.T) {
+	quote := func(s string) string { return "\"" + s + "\"" }
+	tests := []struct{ in, want string }{
+		{"count(*)>5", "count(*)>5"},
+		{"sum(total)>100", "sum(\"total\")>100"},
+		{"max(price) > min( cost )", "max(\"price\") > min(\"cost\")"},
+		{"name = 'f(x)'", "name = 'f(x)'"},
+		{"(a > 1)", "(a > 1)"},
+	}
+	for _, tt := range tests {
+		if got := QuoteAggregates(tt.in, quote); got != tt.want {
+			t.Errorf("QuoteAggregates(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNegativeSlice(t *testing.T) {
+	tests := []struct {
+		url    string
+		limit  string
+		offset string
+	}{
+		// last 10 of 100 rows
+		{"data.sqlite;users[-10:]", "10", "90"},
+		// all but the last 5
+		{"data.sqlite;users[:-5]", "95", "0"},
+		{"data.sqlite;users[-20:-5]", "15", "80"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if !b.FromEnd {
+			t.Errorf("%s: expected FromEnd", tt.url)
+		}
+		if b.Limit != "" || b.Offset != "" {
+			t.Errorf("%s: expected no Limit/Offset before resolving, got %q/%q", tt.url, b.Limit, b.Offset)
+		}
+		b.ResolveFromEnd(100)
+		if b.Limit != tt.limit || b.Offset != tt.offset {
+			t.Errorf("%s: resolved Limit/Offset = %q/%q, want %q/%q", tt.url, b.Limit, b.Offset, tt.limit, tt.offset)
+		}
+		if b.FromEnd {
+			t.Errorf("%s: expected FromEnd cleared after resolving", tt.url)
+		}
+	}
+
+	// Fewer rows than requested clamps at the start
+	b, err := ParseBanquet("data.sqlite;users[-10:]")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	b.ResolveFromEnd(4)
+	if b.Limit != "4" || b.Offset != "0" {
+		t.Errorf("Expected Limit/Offset 4/0, got %q/%q", b.Limit, b.Offset)
+	}
+
+	// MaxLimit caps the resolved slice
+	b, err = ParseBanquetWithOptions("data.sqlite;users[-100000:]", ParseOptions{MaxLimit: 100})
+	if err != nil {
+		t.Fatalf("ParseBanquetWithOptions failed: %v", err)
+	}
+	if !b.FromEnd {
+		t.Error("expected FromEnd with MaxLimit")
+	}
+	b.ResolveFromEnd(200000)
+	if b.Limit != "100" || b.Offset != "100000" {
+		t.Errorf("Expected Limit/Offset 100/100000, got %q/%q", b.Limit, b.Offset)
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users[1:2:3:4]", "slice [1:2:3:4] ignored: too many colons"},
+		{"data.sqlite;users?limit=abc", `limit value "abc" ignored`},
+		{"data.sqlite;users?limit=10&offset=x", `offset value "x" ignored`},
+		{"data.sqlite;users[10:20]?top=5", "slice ignored: top=5 takes precedence"},
+		{"data.sqlite;users[10:20]?offset=5", "slice [10:20] ignored: limit and offset query params take precedence"},
+		{"data.sqlite;users?top=five", `limit value "five" ignored`},
+	}
+	for _, tt := range tests {
+		// A malformed slice is only ignored, with a warning, in tolerant mode
+		b, err := ParseBanquetWithOptions(tt.url, ParseOptions{TolerantSlice: true})
+		if err != nil {
+			t.Fatalf("ParseBanquetWithOptions(%q) failed: %v", tt.url, err)
+		}
+		if !slices.Contains(b.Warnings, tt.want) {
+			t.Errorf("%s: Warnings = %q, want %q", tt.url, b.Warnings, tt.want)
+		}
+	}
+
+	b, err := ParseBanquet("data.sqlite;users?limit=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Limit != "" || b.Table != "users" {
+		t.Errorf("expected tolerant parse with no Limit, got Table %q Limit %q", b.Table, b.Limit)
+	}
+	if b, _ := ParseBanquet("data.sqlite;users[10:20]"); len(b.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %q", b.Warnings)
+	}
+}
+
+func TestMalformedSlice(t *testing.T) {
+	for _, u := range []string{"data.sqlite;users[abc:10]", "data.sqlite;users[-10]", "data.sqlite;users[1:2:3:4]"} {
+		// Malformed slices fail by default
+		var verr *ValidationError
+		if _, err := ParseBanquet(u); !errors.As(err, &verr) || verr.Field != "Slice" {
+			t.Errorf("%s: expected a Slice *ValidationError, got %v", u, err)
+		}
+
+		// Tolerant mode ignores the slice but records why
+		b, err := ParseBanquetWithOptions(u, ParseOptions{TolerantSlice: true})
+		if err != nil {
+			t.Fatalf("ParseBanquetWithOptions(%q) failed: %v", u, err)
+		}
+		if b.Limit != "" || b.Offset != "" {
+			t.Errorf("%s: expected no Limit/Offset, got %q/%q", u, b.Limit, b.Offset)
+		}
+		if len(b.Errors) != 1 || !errors.As(b.Errors[0], &verr) || verr.Field != "Slice" {
+			t.Errorf("%s: expected one Slice ValidationError, got %v", u, b.Errors)
+		}
+	}
+
+	// Nested URLs report the inner error too
+	for _, u := range []string{"http://localhost/data.csv;id[abc:1]", "http://gw/https://edge/data.csv;id[1:2:3:4]"} {
+		var verr *ValidationError
+		if _, err := ParseNested(u); !errors.As(err, &verr) || verr.Field != "Slice" {
+			t.Errorf("ParseNested(%q): expected a Slice *ValidationError, got %v", u, err)
+		}
+		if _, err := ParseNestedN(u, 2); !errors.As(err, &verr) || verr.Field != "Slice" {
+			t.Errorf("ParseNestedN(%q): expected a Slice *ValidationError, got %v", u, err)
+		}
+	}
+
+	// Brackets that aren't slices are not errors
+	b, err := ParseBanquet("data.sqlite;users;[^]")
+	if err != nil || len(b.Errors) != 0 {
+		t.Errorf("Expected [^] to be ignored, got %v, %v", err, b.Errors)
+	}
+}
+
+func TestParseNestedN(t *testing.T) {
+	tests := []struct {
+		url      string
+		maxDepth int
+		scheme   string
+		host     string
+		path     string
+		table    string
+	}{
+		{"http://gw/https://edge/gs:/bucket/file.csv", 2, "gs", "bucket", "/file.csv", ""},
+		{"http://gw/https://edge/gs:/bucket/file.csv", 1, "https", "edge", "/gs:/bucket/file.csv", ""},
+		{"http://a/http://b/https://c/data.sqlite;users", 3, "https", "c", "/data.sqlite;users", "users"},
+		{"http://a/http://b/https://c/data.sqlite;users", 10, "https", "c", "/data.sqlite;users", "users"},
+		{"http://a/http://b/https://c/data.sqlite;users", 0, "http", "b", "/https://c/data.sqlite;users", ""},
+		{"http://gw/data.sqlite;users", 5, "", "", "data.sqlite;users", "users"},
+	}
+	for _, tt := range tests {
+		b, err := ParseNestedN(tt.url, tt.maxDepth)
+		if err != nil {
+			t.Fatalf("ParseNestedN(%q, %d) error: %v", tt.url, tt.maxDepth, err)
+		}
+		if b.Scheme != tt.scheme || b.Host != tt.host || b.Path != tt.path {
+			t.Errorf("ParseNestedN(%q, %d) = %s %s %s, want %s %s %s", tt.url, tt.maxDepth, b.Scheme, b.Host, b.Path, tt.scheme, tt.host, tt.path)
+		}
+		if tt.table != "" && b.Table != tt.table {
+			t.Errorf("ParseNestedN(%q, %d) Table = %q, want %q", tt.url, tt.maxDepth, b.Table, tt.table)
+		}
+	}
+}
+
+func TestStrictAmbiguousPath(t *testing.T) {
+	// Tolerant mode guesses that the single segment is a table
+	b, err := ParseBanquet("data.sqlite/tableorcolumn")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if b.Table != "tableorcolumn" {
+		t.Errorf("Table = %q, want %q", b.Table, "tableorcolumn")
+	}
+
+	_, err = ParseBanquetWithOptions("data.sqlite/tableorcolumn", ParseOptions{Strict: true})
+	if !errors.Is(err, ErrAmbiguousPath) {
+		t.Errorf("Expected ErrAmbiguousPath, got %v", err)
+	}
+
+	// Explicit tiers, a dataset alone and clear column lists are not ambiguous
+	for _, raw := range []string{"data.sqlite;tableorcolumn", "data.sqlite;users;id,name", "data.sqlite", "data.csv/id,name"} {
+		if _, err := ParseBanquetWithOptions(raw, ParseOptions{Strict: true}); err != nil {
+			t.Errorf("ParseBanquetWithOptions(%q, Strict) error: %v", raw, err)
+		}
+	}
+}
+
+func TestCleanSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		step     CleanStep
+		in       string
+		expected string
+	}{
+		{"TrimLeadingSlash", TrimLeadingSlash, "/gs:/bucket/file.csv", "gs:/bucket/file.csv"},
+		{"TrimLeadingSlash root", TrimLeadingSlash, "/", "."},
+		{"TrimLeadingSlash none", TrimLeadingSlash, "data.csv", "data.csv"},
+		{"NormalizeScheme", NormalizeScheme, "gs:/bucket/file.csv", "gs://bucket/file.csv"},
+		{"NormalizeScheme already", NormalizeScheme, "https://host/file.csv", "https://host/file.csv"},
+		{"NormalizeScheme leading slash untouched", NormalizeScheme, "/gs:/bucket", "/gs://bucket"},
+		{"ProtectColonSegment", ProtectColonSegment, "data.sqlite;users[0:10]", "./data.sqlite;users[0:10]"},
+		{"ProtectColonSegment after slash", ProtectColonSegment, "dir/users[0:10]", "dir/users[0:10]"},
+		{"ProtectColonSegment scheme", ProtectColonSegment, "gs://bucket/file.csv", "gs://bucket/file.csv"},
+	}
+	for _, tt := range tests {
+		if got := tt.step(tt.in); got != tt.expected {
+			t.Errorf("%s(%q) = %q, want %q", tt.name, tt.in, got, tt.expected)
+		}
+	}
+
+	// Slash trim without the scheme rewrite
+	if got := CleanUrlWith("/gs:/bucket/file.csv", TrimLeadingSlash); got != "gs:/bucket/file.csv" {
+		t.Errorf("CleanUrlWith(TrimLeadingSlash) = %q, want %q", got, "gs:/bucket/file.csv")
+	}
+	if got := CleanUrlWith("/data.csv"); got != "/data.csv" {
+		t.Errorf("CleanUrlWith() with no steps = %q, want input unchanged", got)
+	}
+}
+
+func TestLegacyCaret(t *testing.T) {
+	raw := "data.sqlite;users;id,name,^age,!^score"
+
+	b, err := ParseBanquet(raw)
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if b.OrderBy != "" || len(b.Select) != 4 {
+		t.Errorf("Expected literal caret columns by default, got Select %v OrderBy %q", b.Select, b.OrderBy)
+	}
+
+	b, err = ParseBanquetWithOptions(raw, ParseOptions{LegacyCaret: true})
+	if err != nil {
+		t.Fatalf("ParseBanquetWithOptions error: %v", err)
+	}
+	if b.OrderBy != "age" || b.SortDirection != "DESC" {
+		t.Errorf("OrderBy = %q %q, want age DESC", b.OrderBy, b.SortDirection)
+	}
+	wantSorts := []OrderTerm{{Column: "age", Direction: "DESC"}, {Column: "score", Direction: "ASC"}}
+	if len(b.Sorts) != 2 || b.Sorts[0] != wantSorts[0] || b.Sorts[1] != wantSorts[1] {
+		t.Errorf("Sorts = %v, want %v", b.Sorts, wantSorts)
+	}
+	if len(b.Select) != 2 || b.Select[0] != "id" || b.Select[1] != "name" {
+		t.Errorf("Select = %v, want [id name]", b.Select)
+	}
+}
+
+func TestSchemaQualifiedTable(t *testing.T) {
+	tests := []struct {
+		url    string
+		schema string
+		table  string
+		sel    []string
+	}{
+		{"db;public.users;id", "public", "users", []string{"id"}},
+		{"data.sqlite;users", "", "users", []string{"*"}},
+		{"data.sqlite/main.users", "main", "users", []string{"*"}},
+		{"db;catalog.public.users", "catalog.public", "users", []string{"*"}},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if b.Schema != tt.schema || b.Table != tt.table {
+			t.Errorf("ParseBanquet(%q) Schema, Table = %q, %q, want %q, %q", tt.url, b.Schema, b.Table, tt.schema, tt.table)
+		}
+		if len(b.Select) != len(tt.sel) || b.Select[0] != tt.sel[0] {
+			t.Errorf("ParseBanquet(%q) Select = %v, want %v", tt.url, b.Select, tt.sel)
+		}
+	}
+}
+
+func TestQueryParamTableSelectDistinct(t *testing.T) {
+	tests := []struct {
+		url      string
+		table    string
+		sel      []string
+		distinct bool
+	}{
+		{"data.sqlite?table=users&select=id,%20name", "users", []string{"id", "name"}, false},
+		{"data.sqlite?table=public.users&distinct=true", "users", []string{"*"}, true},
+		// The query param replaces the heuristic table
+		{"data.sqlite/orders?table=users", "users", []string{"orders"}, false},
+		// but not an explicit semicolon tier
+		{"data.sqlite;orders?table=users", "orders", []string{"*"}, false},
+		// Path columns take precedence over the select param
+		{"data.sqlite;users;id?select=name,email", "users", []string{"id"}, false},
+		{"21mb.csv?select=name,email,phone&limit=50", "", []string{"name", "email", "phone"}, false},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if b.Table != tt.table {
+			t.Errorf("ParseBanquet(%q) Table = %q, want %q", tt.url, b.Table, tt.table)
+		}
+		if fmt.Sprint(b.Select) != fmt.Sprint(tt.sel) {
+			t.Errorf("ParseBanquet(%q) Select = %v, want %v", tt.url, b.Select, tt.sel)
+		}
+		if b.Distinct != tt.distinct {
+			t.Errorf("ParseBanquet(%q) Distinct = %v, want %v", tt.url, b.Distinct, tt.distinct)
+		}
+	}
+}
+
+func TestSelectAll(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"data.sqlite;users", false},
+		{"data.sqlite;users;*", true},
+		{"data.sqlite;users;*,!password", true},
+		{"data.sqlite;users?select=*", true},
+		{"data.sqlite;users;id,name", false},
+		{"data.csv;*", true},
+		{"data.csv", false},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if b.SelectAll != tt.want {
+			t.Errorf("%s: SelectAll = %v, want %v (Select %q)", tt.url, b.SelectAll, tt.want, b.Select)
+		}
+	}
+}
+
+func TestEscapedTierSeparator(t *testing.T) {
+	tests := []struct{ url, dataset, table, columns string }{
+		{`weird\;name.csv;users`, "weird;name.csv", "", "users"},
+		{`dir\;v2/data.sqlite;users;id`, "dir;v2/data.sqlite", "users", "id"},
+		{`weird\;name.csv/id`, "weird;name.csv", "id", "id"},
+		{`weird%5C;name.csv;id`, "weird;name.csv", "", "id"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if b.DataSetPath != tt.dataset || b.Table != tt.table || b.ColumnPath != tt.columns {
+			t.Errorf("%s: got %q/%q/%q, want %q/%q/%q", tt.url, b.DataSetPath, b.Table, b.ColumnPath, tt.dataset, tt.table, tt.columns)
+		}
+		r, err := ParseBanquet(Unparse(b))
+		if err != nil || r.DataSetPath != tt.dataset {
+			t.Errorf("%s: Unparse %q reparsed to dataset %q (%v)", tt.url, Unparse(b), r.DataSetPath, err)
+		}
+	}
+}
+
+func TestBareSlice(t *testing.T) {
+	tests := []struct {
+		url           string
+		selects       []string
+		limit, offset string
+	}{
+		{"data.sqlite;users;0:10", []string{"*"}, "10", "0"},
+		{"data.csv;id,name,5:15", []string{"id", "name"}, "10", "5"},
+		{"data.sqlite/users/id/0:10", []string{"id"}, "10", "0"},
+		// Bracketed slices win, and non-integer parts are not a slice
+		{"data.sqlite;users;id[2:4],0:10", []string{"id", "0:10"}, "2", "2"},
+		{"data.sqlite;users;id:name", []string{"id:name"}, "", ""},
+		{"data.sqlite;users;0:10,id", []string{"0:10", "id"}, "", ""},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if fmt.Sprint(b.Select) != fmt.Sprint(tt.selects) || b.Limit != tt.limit || b.Offset != tt.offset {
+			t.Errorf("%s: Select %q Limit %q Offset %q, want %q %q %q", tt.url, b.Select, b.Limit, b.Offset, tt.selects, tt.limit, tt.offset)
+		}
+	}
+
+	// A select column left looking like a slice must not become one when unparsed
+	b, err := ParseBanquet("data.sqlite;users;id,0:0,0:10")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	r, err := ParseBanquet(Unparse(b))
+	if err != nil || fmt.Sprint(r.Select) != fmt.Sprint(b.Select) || r.Limit != b.Limit {
+		t.Errorf("Unparse = %q reads back Select %q Limit %q (%v), want %q %q", Unparse(b), r.Select, r.Limit, err, b.Select, b.Limit)
+	}
+}
+
+func TestScalarFunctionColumns(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;users;upper(name),substr(name,1,3),+date(created_at)")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	if want := []string{"upper(name)", "substr(name,1,3)"}; !slices.Equal(b.Select, want) {
+		t.Errorf("Select = %q, want %q", b.Select, want)
+	}
+	if len(b.Sorts) != 1 || b.Sorts[0] != (OrderTerm{Column: "date(created_at)", Direction: "ASC"}) {
+		t.Errorf("Sorts = %+v", b.Sorts)
+	}
+
+	quote := func(s string) string { return `"` + s + `"` }
+	for in, want := range map[string]string{
+		"upper(name)":               `upper("name")`,
+		"ROUND(price, 2)":           `ROUND("price", 2)`,
+		"date(created_at,'-1 day')": `date("created_at", '-1 day')`,
+		"name":                      `"name"`,
+		"upper(lower(name))":        `"upper(lower(name))"`,
+		"exec(name)":                `"exec(name)"`,
+	} {
+		if got := QuoteColumn(in, quote); got != want {
+			t.Errorf("QuoteColumn(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestFprint(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;users;id?limit=5")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	var buf bytes.Buffer
+	Fprint(&buf, b)
+	for _, want := range []string{"rawurl: data.sqlite;users;id?limit=5\n", "  DataSetPath: data.sqlite\n", "  Table:      \"users\"\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Fprint() output is missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	if got := FmtSprintf(b); strings.Contains(got, `\n`) || !strings.HasPrefix(got, "rawurl: data.sqlite;users;id?limit=5\n  S:") {
+		t.Errorf("FmtSprintf() = %q", got)
+	}
+}
+
+func TestQuoteInLists(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"status in (active,pending)", "status in ('active', 'pending')"},
+		{"status NOT IN (active, on hold) AND age>18", "status NOT IN ('active', 'on hold') AND age>18"},
+		{"id in(1,2.5,-3)", "id in(1, 2.5, -3)"},
+		// Already quoted lists, subqueries and lookalikes are left alone
+		{"status in ('active','pending')", "status in ('active','pending')"},
+		{"id in (select id from vip)", "id in (select id from vip)"},
+		{"name = 'in (x)' AND min(a)>1", "name = 'in (x)' AND min(a)>1"},
+		{"index(x) > 1", "index(x) > 1"},
+		{"id in ()", "id in ()"},
+		// Keywords stay bare
+		{"flag in (true,false)", "flag in (true, false)"},
+		{"x in (col_a,NULL)", "x in ('col_a', NULL)"},
+	}
+	for _, tt := range tests {
+		if got := QuoteInLists(tt.in); got != tt.want {
+			t.Errorf("QuoteInLists(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// A bareword may be a column, so ParseBanquet only quotes lists when asked to
+	for _, where := range []string{"x in (col_a,NULL)", "flag in (true,false)", "status in (active,pending)"} {
+		b, err := ParseBanquet("data.sqlite;users?where=" + url.PathEscape(where))
+		if err != nil {
+			t.Fatalf("ParseBanquet failed: %v", err)
+		}
+		if b.Where != where {
+			t.Errorf("Where = %q, want %q", b.Where, where)
+		}
+	}
+	b, err := ParseBanquetWithOptions("data.sqlite;users?where=status%20in%20(active,pending)", ParseOptions{QuoteInLists: true})
+	if err != nil {
+		t.Fatalf("ParseBanquetWithOptions failed: %v", err)
+	}
+	if want := "status in ('active', 'pending')"; b.Where != want {
+		t.Errorf("Where = %q, want %q", b.Where, want)
+	}
+}
+
+func TestLimitOffsetInt(t *testing.T) {
+	tests := []struct {
+		limit, offset string
+		wantLimit     int
+		wantOffset    int
+	}{
+		{"", "", 100, 0},
+		{"10", "20", 10, 20},
+		{"0", "0", 0, 0},
+		{"abc", "1.5", 100, 0},
+		{"-5", "-1", 100, 0},
+	}
+	for _, tt := range tests {
+		b := &Banquet{Limit: tt.limit, Offset: tt.offset}
+		if got := b.LimitInt(100); got != tt.wantLimit {
+			t.Errorf("LimitInt(100) with Limit %q = %d, want %d", tt.limit, got, tt.wantLimit)
+		}
+		if got := b.OffsetInt(0); got != tt.wantOffset {
+			t.Errorf("OffsetInt(0) with Offset %q = %d, want %d", tt.offset, got, tt.wantOffset)
+		}
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		url     string
+		format  string
+		table   string
+		columns []string
+	}{
+		{"data.sqlite;users.csv", "csv", "users", []string{"*"}},
+		{"data.sqlite;main.users.JSON", "json", "users", []string{"*"}},
+		{"data.sqlite;users;id,name.json", "json", "users", []string{"id", "name"}},
+		{"data/sales.csv;;amount.csv", "csv", "", []string{"amount"}},
+		// Not a directive: the dataset extension, a condition value and heuristic paths
+		{"data.csv", "", "", []string{"*"}},
+		{"data.sqlite;files;name,path=a.csv", "", "files", []string{"name"}},
+		{"data.sqlite/users.json", "", "json", []string{"*"}}, // schema users, table json
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if b.OutputFormat != tt.format || b.Table != tt.table || !slices.Equal(b.Select, tt.columns) {
+			t.Errorf("%s: OutputFormat/Table/Select = %q/%q/%q, want %q/%q/%q", tt.url, b.OutputFormat, b.Table, b.Select, tt.format, tt.table, tt.columns)
+		}
+		if again, err := ParseBanquet(Unparse(b)); err != nil || again.OutputFormat != b.OutputFormat {
+			t.Errorf("%s: Unparse() = %q lost the output format", tt.url, Unparse(b))
+		}
+	}
+}
+
+func TestDedupeSelect(t *testing.T) {
+	tests := []struct {
+		url         string
+		kept, dedup []string
+	}{
+		{"data.sqlite;users;id,name,id", []string{"id", "name", "id"}, []string{"id", "name"}},
+		{"data.sqlite;users;name,id,name,Name,id", []string{"name", "id", "name", "Name", "id"}, []string{"name", "id", "Name"}},
+		{"data.sqlite;users?select=id,id", []string{"id", "id"}, []string{"id"}},
+		{"data.sqlite;users;id,name", []string{"id", "name"}, []string{"id", "name"}},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if !slices.Equal(b.Select, tt.kept) {
+			t.Errorf("%s: Select = %q, want %q", tt.url, b.Select, tt.kept)
+		}
+		b, err = ParseBanquetWithOptions(tt.url, ParseOptions{DedupeSelect: true})
+		if err != nil {
+			t.Fatalf("ParseBanquetWithOptions(%q) failed: %v", tt.url, err)
+		}
+		if !slices.Equal(b.Select, tt.dedup) {
+			t.Errorf("%s: deduped Select = %q, want %q", tt.url, b.Select, tt.dedup)
+		}
+	}
+}
+
+func TestLimitCaps(t *testing.T) {
+	tests := []struct {
+		url     string
+		opts    ParseOptions
+		limit   string
+		warning string
+	}{
+		{"data.sqlite;users?limit=1000000", ParseOptions{MaxLimit: 500}, "500", "limit 1000000 capped at 500"},
+		{"data.sqlite;users[0:1000]", ParseOptions{MaxLimit: 500}, "500", "limit 1000 capped at 500"},
+		{"data.sqlite;users?limit=20", ParseOptions{MaxLimit: 500}, "20", ""},
+		{"data.sqlite;users", ParseOptions{DefaultLimit: 100, MaxLimit: 500}, "100", ""},
+		{"data.sqlite;users?limit=7", ParseOptions{DefaultLimit: 100}, "7", ""},
+		{"data.sqlite;users", ParseOptions{DefaultLimit: 1000, MaxLimit: 500}, "500", "limit 1000 capped at 500"},
+		// No limit at all still can't get past MaxLimit
+		{"data.sqlite;users", ParseOptions{MaxLimit: 500}, "500", ""},
+		{"data.sqlite;users?offset=5", ParseOptions{MaxLimit: 500}, "500", ""},
+		{"data.sqlite;users[-100000:]", ParseOptions{MaxLimit: 500}, "500", ""},
+		// Unlimited by default
+		{"data.sqlite;users?limit=1000000", ParseOptions{}, "1000000", ""},
+		{"data.sqlite;users", ParseOptions{}, "", ""},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquetWithOptions(tt.url, tt.opts)
+		if err != nil {
+			t.Fatalf("ParseBanquetWithOptions(%q) failed: %v", tt.url, err)
+		}
+		if b.Limit != tt.limit {
+			t.Errorf("%s %+v: Limit = %q, want %q", tt.url, tt.opts, b.Limit, tt.limit)
+		}
+		if tt.warning == "" && len(b.Warnings) > 0 || tt.warning != "" && !slices.Contains(b.Warnings, tt.warning) {
+			t.Errorf("%s %+v: Warnings = %q, want %q", tt.url, tt.opts, b.Warnings, tt.warning)
+		}
+	}
+}
+
+func TestPreservesCasing(t *testing.T) {
+	tests := []struct {
+		url     string
+		dataset string
+		table   string
+		columns []string
+	}{
+		{"Users.CSV;ID,Name", "Users.CSV", "", []string{"ID", "Name"}},
+		{"Data/Users.CSV/ID,Name", "Data/Users.CSV", "", []string{"ID", "Name"}},
+		{"Shop.SQLite;Orders;OrderID,+CreatedAt", "Shop.SQLite", "Orders", []string{"OrderID"}},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if b.DataSetPath != tt.dataset || b.Table != tt.table || !slices.Equal(b.Select, tt.columns) {
+			t.Errorf("%s: DataSetPath/Table/Select = %q/%q/%q, want %q/%q/%q", tt.url, b.DataSetPath, b.Table, b.Select, tt.dataset, tt.table, tt.columns)
+		}
+	}
+}
+
+func TestSliceQueryParamPrecedence(t *testing.T) {
+	tests := []struct {
+		url           string
+		limit, offset string
+	}{
+		// Either param replaces the whole slice, not just its half
+		{"data.sqlite;users[10:20]?limit=5", "5", ""},
+		{"data.sqlite;users[10:20]?offset=5", "", "5"},
+		{"data.sqlite;users[10:20]?limit=5&offset=30", "5", "30"},
+		{"data.sqlite;users[-10:]?limit=5", "5", ""},
+		// Without params the slice sets both
+		{"data.sqlite;users[10:20]", "10", "10"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if b.Limit != tt.limit || b.Offset != tt.offset || b.FromEnd {
+			t.Errorf("%s: Limit/Offset/FromEnd = %q/%q/%v, want %q/%q/false", tt.url, b.Limit, b.Offset, b.FromEnd, tt.limit, tt.offset)
+		}
+	}
+}
+
+func TestTableSource(t *testing.T) {
+	tests := []struct {
+		url    string
+		source TableSource
+	}{
+		{"data.sqlite;users", TableExplicit},
+		{"data.sqlite;main.users;id", TableExplicit},
+		{"data.sqlite?table=users", TableExplicit},
+		{"data.sqlite/users", TableHeuristic},
+		// A flat file has no table until a composer infers one
+		{"users.csv", TableUnset},
+		{"users.csv;;id,name", TableUnset},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if b.TableSource != tt.source {
+			t.Errorf("%s: TableSource = %v, want %v", tt.url, b.TableSource, tt.source)
+		}
+	}
+}
+
+func TestTierSeparator(t *testing.T) {
+	tests := []struct {
+		url     string
+		sep     string
+		dataset string
+	}{
+		{"data.sqlite::users::id,name,-age,status!=active", "::", "data.sqlite"},
+		{"data.sqlite|users|id,name,-age,status!=active", "|", "data.sqlite"},
+		{"gs://bucket/data.sqlite::users::id,name,-age,status!=active?limit=5", "::", "/data.sqlite"},
+	}
+	want, err := ParseBanquet("data.sqlite;users;id,name,-age,status!=active")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquetWithOptions(tt.url, ParseOptions{TierSeparator: tt.sep})
+		if err != nil {
+			t.Fatalf("ParseBanquetWithOptions(%q) failed: %v", tt.url, err)
+		}
+		if b.Table != want.Table || !slices.Equal(b.Select, want.Select) || b.Where != want.Where || b.OrderBy != want.OrderBy {
+			t.Errorf("%s: Table/Select/Where/OrderBy = %q/%q/%q/%q, want %q/%q/%q/%q", tt.url,
+				b.Table, b.Select, b.Where, b.OrderBy, want.Table, want.Select, want.Where, want.OrderBy)
+		}
+		if b.DataSetPath != tt.dataset {
+			t.Errorf("%s: DataSetPath = %q, want %q", tt.url, b.DataSetPath, tt.dataset)
+		}
+	}
+
+	// The column tier keeps | as OR, and a literal ; stays in the dataset name
+	b, err := ParseBanquetWithOptions("we;ird.sqlite|orders|kind=a|kind=b", ParseOptions{TierSeparator: "|"})
+	if err != nil {
+		t.Fatalf("ParseBanquetWithOptions failed: %v", err)
+	}
+	if b.DataSetPath != "we;ird.sqlite" || b.Table != "orders" || len(b.Conditions) != 1 || len(b.Conditions[0].Or) != 2 {
+		t.Errorf("DataSetPath/Table/Conditions = %q/%q/%+v", b.DataSetPath, b.Table, b.Conditions)
+	}
+}
+
+func TestTupleInErrors(t *testing.T) {
+	tests := []string{
+		"data.sqlite;orders;(region,product)=[(west,widget),(east,gadget]",
+		"data.sqlite;orders;(region,product=[(west,widget)]",
+		"data.sqlite;orders;(region,product)=[(west,widget,extra)]",
+		"data.sqlite;orders;(region,product)=[]",
+	}
+	for _, u := range tests {
+		b, err := ParseBanquet(u)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", u, err)
+		}
+		var verr *ValidationError
+		if len(b.Errors) != 1 || !errors.As(b.Errors[0], &verr) || verr.Field != "Conditions" {
+			t.Errorf("%s: Errors = %v, want a Conditions *ValidationError", u, b.Errors)
+		}
+		if b.Where != "" {
+			t.Errorf("%s: Where = %q, want the malformed condition dropped", u, b.Where)
+		}
+		if _, err := ParseBanquetWithOptions(u, ParseOptions{Strict: true}); !errors.As(err, &verr) {
+			t.Errorf("%s: Strict parse error = %v, want *ValidationError", u, err)
+		}
+	}
+}
+
+func TestIsNested(t *testing.T) {
+	tests := []struct {
+		url    string
+		nested bool
+		scheme string
+		table  string
+	}{
+		{"http://localhost:8080/gs://bucket/data.sqlite;users", true, "gs", "users"},
+		{"http://localhost:8080/gs:/bucket/data.sqlite;users", true, "gs", "users"},
+		{"/http://gw/https://edge/data.sqlite;users?limit=5", true, "https", "users"},
+		{"/https://edge/data.sqlite;users?limit=5", false, "https", "users"},
+		{"gs://bucket/data.sqlite;users", false, "gs", "users"},
+		{"data.sqlite;users;name:desc", false, "", "users"},
+		{"http://localhost:8080/data.sqlite;users?where=url='http://x'", false, "http", "users"},
+	}
+	for _, tt := range tests {
+		if got := IsNested(tt.url); got != tt.nested {
+			t.Errorf("IsNested(%q) = %v, want %v", tt.url, got, tt.nested)
+		}
+		b, err := ParseAuto(tt.url)
+		if err != nil {
+			t.Fatalf("ParseAuto(%q) error: %v", tt.url, err)
+		}
+		if b.Scheme != tt.scheme || b.Table != tt.table {
+			t.Errorf("ParseAuto(%q) Scheme/Table = %q/%q, want %q/%q", tt.url, b.Scheme, b.Table, tt.scheme, tt.table)
+		}
+	}
+}
+
+func TestMixedSlashAndTierPaths(t *testing.T) {
+	tests := []struct {
+		url     string
+		dataset string
+		table   string
+		columns []string
+		warning string
+	}{
+		{"data/sales.csv;;id,amount", "data/sales.csv", "", []string{"id", "amount"}, ""},
+		{"gs://bucket/exports/db.sqlite;users;id", "/exports/db.sqlite", "users", []string{"id"}, ""},
+		{"archive.zip/inner;users;id", "archive.zip/inner", "users", []string{"id"}, ""},
+		{"gs://project/dataset;users;id", "/dataset", "users", []string{"id"}, ""},
+		// The semicolon tiers win, but a dataset running past its file is flagged
+		{"data.csv/extra;users;id,name", "data.csv/extra", "users", []string{"id", "name"},
+			`dataset "data.csv/extra" continues past data.csv; the table tier starts at the first ';'`},
+		{"dir/data.sqlite/main;users", "dir/data.sqlite/main", "users", []string{"*"},
+			`dataset "dir/data.sqlite/main" continues past data.sqlite; the table tier starts at the first ';'`},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if b.DataSetPath != tt.dataset || b.Table != tt.table || !slices.Equal(b.Select, tt.columns) {
+			t.Errorf("%s: DataSetPath/Table/Select = %q/%q/%q, want %q/%q/%q", tt.url, b.DataSetPath, b.Table, b.Select, tt.dataset, tt.table, tt.columns)
+		}
+		if tt.warning == "" && len(b.Warnings) > 0 || tt.warning != "" && !slices.Contains(b.Warnings, tt.warning) {
+			t.Errorf("%s: Warnings = %q, want %q", tt.url, b.Warnings, tt.warning)
+		}
+		if b.TableSource != TableExplicit && tt.table != "" {
+			t.Errorf("%s: TableSource = %v, want TableExplicit", tt.url, b.TableSource)
+		}
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	tests := []struct {
+		url   string
+		opts  ParseOptions
+		limit string
+	}{
+		{"data.sqlite;users?limit=all", ParseOptions{}, ""},
+		{"data.sqlite;users?limit=ALL&offset=20", ParseOptions{}, ""},
+		{"data.sqlite;users?limit=-1", ParseOptions{}, ""},
+		// Explicitly unlimited is not the same as unset: DefaultLimit doesn't apply, MaxLimit does
+		{"data.sqlite;users?limit=all", ParseOptions{DefaultLimit: 50}, ""},
+		{"data.sqlite;users", ParseOptions{DefaultLimit: 50}, "50"},
+		{"data.sqlite;users?limit=-1", ParseOptions{MaxLimit: 100}, "100"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquetWithOptions(tt.url, tt.opts)
+		if err != nil {
+			t.Fatalf("ParseBanquetWithOptions(%q) failed: %v", tt.url, err)
+		}
+		if b.Limit != tt.limit {
+			t.Errorf("%s %+v: Limit = %q, want %q", tt.url, tt.opts, b.Limit, tt.limit)
+		}
+		if tt.opts.MaxLimit == 0 && len(b.Warnings) > 0 {
+			t.Errorf("%s: Warnings = %q, want none", tt.url, b.Warnings)
+		}
+	}
+}
+
+func TestOrderByPositionValidation(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;users;id,name,+3,-1")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	var verr *ValidationError
+	if len(b.Errors) != 1 || !errors.As(b.Errors[0], &verr) || verr.Field != "OrderBy" || verr.Value != "3" {
+		t.Errorf("Errors = %v, want an OrderBy *ValidationError for 3", b.Errors)
+	}
+	if want := []OrderTerm{{Column: "1", Direction: "DESC"}}; !slices.Equal(b.Sorts, want) || b.OrderBy != "1" {
+		t.Errorf("Sorts/OrderBy = %+v/%q, want %+v/%q", b.Sorts, b.OrderBy, want, "1")
+	}
+	if _, err := ParseBanquetWithOptions("data.sqlite;users;id,name,+3", ParseOptions{Strict: true}); !errors.As(err, &verr) {
+		t.Errorf("Strict parse error = %v, want *ValidationError", err)
+	}
+	// In range, or against *, positions are fine
+	for _, u := range []string{"data.sqlite;users;id,name,+2", "data.sqlite;users;+9", "data.sqlite;users;*,!ssn,+9"} {
+		if b, err := ParseBanquetWithOptions(u, ParseOptions{Strict: true}); err != nil || len(b.Errors) > 0 {
+			t.Errorf("ParseBanquet(%q) = %v, %v, want no errors", u, err, b)
+		}
+	}
+}
+
+func TestExtraParams(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;users;id?orderid=1&limit=10&tag=prime+val&where=age>1&tag=b%26c&format=csv&orderid=2")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	want := []QueryParam{{"orderid", "1"}, {"tag", "prime val"}, {"tag", "b&c"}, {"orderid", "2"}}
+	if !slices.Equal(b.Extra, want) {
+		t.Errorf("Extra = %+v, want %+v", b.Extra, want)
+	}
+	if b.Limit != "10" {
+		t.Errorf("Limit = %q, want %q", b.Limit, "10")
+	}
+
+	b, err = ParseBanquet("data.sqlite;users?limit=5&offset=10")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	if b.Extra != nil {
+		t.Errorf("Extra = %+v, want none", b.Extra)
+	}
+}
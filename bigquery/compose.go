@@ -0,0 +1,195 @@
+package bigquery
+
+// Separate package for the BigQuery dialect. Tables are addressed as `project.dataset.table`
+// and identifiers are quoted with backticks.
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/darianmavgo/banquet"
+)
+
+// noLimit stands in for a missing LIMIT, which BigQuery requires before OFFSET.
+const noLimit = "9223372036854775807" // math.MaxInt64
+
+// Options controls BigQuery specific output.
+type Options struct {
+	// Types declares column types so path condition values are quoted by type rather than
+	// by whether they look like numbers, as in sqlite.Options.
+	Types map[string]banquet.ColumnType
+
+	// bind, set by ComposeArgs, writes each quoted condition value as an @pN placeholder and
+	// collects the value.
+	bind func(string) string
+}
+
+// Compose builds a BigQuery Standard SQL query string from a Banquet struct.
+func Compose(bq *banquet.Banquet) string {
+	return ComposeWithOptions(bq, Options{})
+}
+
+// ComposeWithOptions builds a BigQuery Standard SQL query string from a Banquet struct honoring opts.
+func ComposeWithOptions(bq *banquet.Banquet, opts Options) string {
+	var parts []string
+
+	// SELECT
+	selectClause := "*"
+	selectCols, err := bq.ExpandSelect(nil)
+	if err != nil || len(bq.Exclude) > 0 && len(selectCols) == 0 {
+		// BigQuery supports SELECT * EXCEPT natively.
+		selectClause = "* EXCEPT (" + quoteList(bq.Exclude) + ")"
+	} else if len(selectCols) > 0 && selectCols[0] != "*" {
+		selectClause = quoteList(selectCols)
+	}
+	if bq.Distinct {
+		selectClause = "DISTINCT " + selectClause
+	}
+	parts = append(parts, "SELECT "+selectClause)
+
+	// FROM
+	if bq.From != nil {
+		parts = append(parts, "FROM ("+ComposeWithOptions(bq.From, opts)+") AS "+QuoteIdentifier(bq.Table))
+	} else {
+		parts = append(parts, "FROM "+QuoteIdentifier(TableName(bq)))
+	}
+
+	// WHERE
+	renderer := banquet.RendererFor(Dialect)
+	renderer.Types = opts.Types
+	if opts.bind != nil {
+		renderer.String = opts.bind
+	}
+	if where := renderer.Where(bq); where != "" {
+		parts = append(parts, "WHERE "+where)
+	}
+
+	// GROUP BY
+	if bq.GroupBy != "" {
+		parts = append(parts, "GROUP BY "+QuoteIdentifier(bq.GroupBy))
+	}
+
+	// HAVING
+	if bq.Having != "" {
+		parts = append(parts, "HAVING "+banquet.QuoteAggregates(bq.Having, QuoteIdentifier))
+	}
+
+	// ORDER BY
+	sorts := bq.Sorts
+	if len(sorts) == 0 && bq.OrderBy != "" {
+		sorts = []banquet.OrderTerm{{Column: bq.OrderBy, Direction: bq.SortDirection}}
+	}
+	if len(sorts) > 0 {
+		parts = append(parts, "ORDER BY "+banquet.RendererFor(Dialect).OrderBy(sorts))
+	}
+
+	// LIMIT
+	if limit := limitFor(bq); limit != "" {
+		parts = append(parts, "LIMIT "+limit)
+	}
+
+	// OFFSET
+	if bq.Offset != "" {
+		parts = append(parts, "OFFSET "+bq.Offset)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ComposeArgs is the bound-args variant of Compose. The string values of path conditions,
+// LIMIT and OFFSET are emitted as named parameters @p1, @p2, ... and returned in args in that
+// order, args[0] being @p1. Numbers and booleans stay inline, and the where and having params
+// remain raw SQL fragments.
+func ComposeArgs(bq *banquet.Banquet) (string, []any) {
+	lenient := *bq
+	lenient.Limit, lenient.Offset = "", ""
+	var args []any
+	query := ComposeWithOptions(&lenient, Options{bind: func(val string) string {
+		args = append(args, val)
+		return "@p" + strconv.Itoa(len(args))
+	}})
+
+	for _, clause := range []struct{ keyword, value string }{{"LIMIT", limitFor(bq)}, {"OFFSET", bq.Offset}} {
+		if clause.value == "" {
+			continue
+		}
+		var arg any = clause.value
+		if n, err := strconv.ParseInt(clause.value, 10, 64); err == nil {
+			arg = n
+		}
+		args = append(args, arg)
+		query += " " + clause.keyword + " @p" + strconv.Itoa(len(args))
+	}
+	return query, args
+}
+
+// limitFor returns the LIMIT of bq, or noLimit when only an offset is set.
+func limitFor(bq *banquet.Banquet) string {
+	if bq.Limit == "" && bq.Offset != "" {
+		return noLimit
+	}
+	return bq.Limit
+}
+
+// TableName builds the fully-qualified project.dataset.table name.
+// The project comes from the Host, e.g. gs://project/dataset.table or gs://project/dataset;table.
+// A schema-qualified table tier (db;dataset.table) names the dataset in place of the path.
+func TableName(bq *banquet.Banquet) string {
+	dataset := strings.Trim(bq.DataSetPath, "/")
+	if bq.Schema != "" {
+		dataset = bq.Schema
+	}
+	var names []string
+	if bq.Host != "" {
+		names = append(names, bq.Host)
+	}
+	if dataset != "" {
+		names = append(names, dataset)
+	}
+	if bq.Table != "" {
+		names = append(names, bq.Table)
+	}
+	return strings.Join(names, ".")
+}
+
+// quoteList quotes and comma joins cols, leaving a * next to explicit columns bare.
+func quoteList(cols []string) string {
+	quotedCols := make([]string, len(cols))
+	for i, col := range cols {
+		quotedCols[i] = col
+		if col != "*" {
+			quotedCols[i] = banquet.QuoteColumn(col, QuoteIdentifier)
+		}
+	}
+	return strings.Join(quotedCols, ", ")
+}
+
+// Dialect is the BigQuery spelling used to render path conditions.
+var Dialect banquet.Dialect = dialect{}
+
+type dialect struct{}
+
+func (dialect) QuoteIdentifier(s string) string { return QuoteIdentifier(s) }
+
+// NotEqual spells banquet.OpNe as !=.
+func (dialect) NotEqual() string { return "!=" }
+
+// NullsOrdering reports that BigQuery accepts NULLS FIRST/LAST.
+func (dialect) NullsOrdering() bool { return true }
+
+// Like is LIKE. BigQuery has no ILIKE, so the match is case-sensitive.
+func (dialect) Like() string { return "LIKE" }
+
+// QuoteString single-quotes s with backslash escapes, the only escaping BigQuery accepts.
+func (dialect) QuoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// QuoteIdentifier wraps a string in backticks and escapes existing backticks and backslashes.
+func QuoteIdentifier(s string) string {
+	if s == "" || s == "*" {
+		return s
+	}
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	return "`" + strings.ReplaceAll(s, "`", "\\`") + "`"
+}
@@ -0,0 +1,186 @@
+package bigquery
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/darianmavgo/banquet"
+)
+
+func TestCompose(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{
+			// Dotted dataset path carries the table
+			url:      "gs://project/dataset.table",
+			expected: "SELECT * FROM `project.dataset.table`",
+		},
+		{
+			// Table tier supplies the table
+			url:      "gs://project/dataset;users;id,name",
+			expected: "SELECT `id`, `name` FROM `project.dataset.users`",
+		},
+		{
+			url:      "gs://project/dataset;users;id,-age?where=active=true&limit=10",
+			expected: "SELECT `id` FROM `project.dataset.users` WHERE active=true ORDER BY `age` DESC LIMIT 10",
+		},
+		{
+			url:      "gs:/project/dataset;users[10:20]",
+			expected: "SELECT * FROM `project.dataset.users` LIMIT 10 OFFSET 10",
+		},
+		{
+			// OFFSET needs a LIMIT in BigQuery
+			url:      "gs://project/dataset;users?offset=20",
+			expected: "SELECT * FROM `project.dataset.users` LIMIT 9223372036854775807 OFFSET 20",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			bq, err := banquet.ParseBanquet(tt.url)
+			if err != nil {
+				t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+			}
+			got := Compose(bq)
+			if got != tt.expected {
+				t.Errorf("Compose() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if got := QuoteIdentifier("we`ird"); got != "`we\\`ird`" {
+		t.Errorf("QuoteIdentifier() = %q, want %q", got, "`we\\`ird`")
+	}
+}
+
+func TestComposeArgs(t *testing.T) {
+	bq, err := banquet.ParseBanquet("gs://project/dataset;users[0:5]")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got, args := ComposeArgs(bq)
+	if want := "SELECT * FROM `project.dataset.users` LIMIT @p1 OFFSET @p2"; got != want {
+		t.Errorf("ComposeArgs() = %q, want %q", got, want)
+	}
+	if len(args) != 2 || args[0] != int64(5) || args[1] != int64(0) {
+		t.Errorf("ComposeArgs() args = %v, want [5 0]", args)
+	}
+
+	bq, err = banquet.ParseBanquet("gs://project/dataset;users?offset=20")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got, args = ComposeArgs(bq)
+	if want := "SELECT * FROM `project.dataset.users` LIMIT @p1 OFFSET @p2"; got != want {
+		t.Errorf("ComposeArgs() = %q, want %q", got, want)
+	}
+	if len(args) != 2 || args[0] != int64(math.MaxInt64) || args[1] != int64(20) {
+		t.Errorf("ComposeArgs() args = %v, want [%d 20]", args, int64(math.MaxInt64))
+	}
+}
+
+func TestComposeExclusions(t *testing.T) {
+	bq, err := banquet.ParseBanquet("gs://project/dataset;users;!password")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := Compose(bq), "SELECT * EXCEPT (`password`) FROM `project.dataset.users`"; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+}
+
+func TestComposeSchemaQualified(t *testing.T) {
+	bq, err := banquet.ParseBanquet("gs://project/db;analytics.users;id")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := Compose(bq), "SELECT `id` FROM `project.analytics.users`"; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+}
+
+func TestDialectNotEqual(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;status!=x")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	want := "`status` != 'x'"
+	if got := banquet.RendererFor(Dialect).Where(bq); got != want {
+		t.Errorf("Where = %q, want %q", got, want)
+	}
+	if got := Compose(bq); !strings.Contains(got, "WHERE "+want) {
+		t.Errorf("Compose() = %q, want WHERE %q", got, want)
+	}
+}
+
+func TestComposeContains(t *testing.T) {
+	bq, err := banquet.ParseBanquet("db;users;id,name~smith")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	want := " LIKE '%smith%'"
+	if got := Compose(bq); !strings.Contains(got, want) {
+		t.Errorf("Compose() = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestComposeBackslashLiteral(t *testing.T) {
+	bq, err := banquet.ParseBanquet(`db;users;name=%5C'%20OR%201=1`)
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	want := "WHERE `name` = '\\\\\\' OR 1=1'"
+	if got := Compose(bq); !strings.HasSuffix(got, want) {
+		t.Errorf("Compose() = %q, want suffix %q", got, want)
+	}
+}
+
+func TestComposeColumnTypes(t *testing.T) {
+	types := map[string]banquet.ColumnType{
+		"zip":    banquet.TypeText,
+		"code":   banquet.TypeNumeric,
+		"active": banquet.TypeBoolean,
+	}
+	bq, err := banquet.ParseBanquet("data.sqlite;users;zip=12345,code=007,active=true")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := ComposeWithOptions(bq, Options{Types: types}), "SELECT * FROM `data.sqlite.users` WHERE `zip` = '12345' AND `code` = 007 AND `active` = TRUE"; got != want {
+		t.Errorf("ComposeWithOptions(Types) = %q, want %q", got, want)
+	}
+}
+
+func TestComposeSubqueryOptions(t *testing.T) {
+	// The caller's options apply inside a subquery as at the top level
+	inner, err := banquet.ParseBanquet("gs://project/dataset;users;id,zip=12345")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	opts := Options{Types: map[string]banquet.ColumnType{"zip": banquet.TypeText}}
+	if got, want := ComposeWithOptions(banquet.Subquery(inner, "u"), opts), "SELECT * FROM (SELECT `id` FROM `project.dataset.users` WHERE `zip` = '12345') AS `u`"; got != want {
+		t.Errorf("ComposeWithOptions(subquery) = %q, want %q", got, want)
+	}
+}
+
+func TestComposeArgsConditions(t *testing.T) {
+	bq, err := banquet.ParseBanquet("gs://project/dataset;users;id,status!=active,name~o'b_,age>30[0:5]")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got, args := ComposeArgs(bq)
+	if want := "SELECT `id` FROM `project.dataset.users` WHERE `status` != @p1 AND `name` LIKE @p2 ESCAPE '!' AND `age` > 30 LIMIT @p3 OFFSET @p4"; got != want {
+		t.Errorf("ComposeArgs() = %q, want %q", got, want)
+	}
+	// Every string value is bound, none is left as a literal
+	if strings.Contains(strings.ReplaceAll(got, "ESCAPE '!'", ""), "'") {
+		t.Errorf("ComposeArgs() = %q has a value literal", got)
+	}
+	if want := []any{"active", "%o'b!_%", int64(5), int64(0)}; !slices.Equal(args, want) {
+		t.Errorf("ComposeArgs() args = %v, want %v", args, want)
+	}
+}
@@ -1,7 +1,13 @@
 package bridge
 
 import (
+	"fmt"
+
 	"github.com/darianmavgo/banquet"
+	"github.com/darianmavgo/banquet/bigquery"
+	"github.com/darianmavgo/banquet/mysql"
+	"github.com/darianmavgo/banquet/postgres"
+	"github.com/darianmavgo/banquet/sqlite"
 )
 
 // Parse takes a raw URL string and returns a parsed BanquetDTO.
@@ -10,7 +16,41 @@ func Parse(rawURL string) (*BanquetDTO, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newDTO(b), nil
+}
+
+// ParseWithSQL is like Parse but also returns the query composed for dialect (sqlite, postgres,
+// mysql or bigquery; empty means sqlite), so one FFI call yields both. The DTO and SQL come
+// from the same parse.
+func ParseWithSQL(rawURL, dialect string) (*BanquetDTO, string, error) {
+	b, err := banquet.ParseBanquet(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	query, err := compose(dialect, b)
+	if err != nil {
+		return nil, "", err
+	}
+	return newDTO(b), query, nil
+}
+
+// compose renders b with the composer for dialect.
+func compose(dialect string, b *banquet.Banquet) (string, error) {
+	switch dialect {
+	case "", "sqlite":
+		return sqlite.Compose(b), nil
+	case "postgres":
+		return postgres.Compose(b), nil
+	case "mysql":
+		return mysql.Compose(b), nil
+	case "bigquery":
+		return bigquery.Compose(b), nil
+	}
+	return "", fmt.Errorf("unknown dialect %q", dialect)
+}
 
+// newDTO copies the transported fields of b.
+func newDTO(b *banquet.Banquet) *BanquetDTO {
 	return &BanquetDTO{
 		Where:         b.Where,
 		Table:         b.Table,
@@ -24,9 +64,24 @@ func Parse(rawURL string) (*BanquetDTO, error) {
 		DataSetPath:   b.DataSetPath,
 		ColumnPath:    b.ColumnPath,
 		OriginalURL:   b.String(),
-	}, nil
+		Auth: AuthDTO{
+			Username: b.Auth.Username,
+			Password: b.Auth.Password,
+			Token:    b.Auth.Token,
+		},
+	}
 }
 
+// Ping returns "pong", so a host can check the library loaded without parsing a URL.
 func Ping() string {
 	return "pong"
 }
+
+// version is the build version, injected with
+// -ldflags "-X github.com/darianmavgo/banquet/bridge.version=v1.2.3".
+var version = "dev"
+
+// Version returns the version the library was built as, "dev" when none was injected.
+func Version() string {
+	return version
+}
@@ -0,0 +1,59 @@
+package bridge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWithSQL(t *testing.T) {
+	tests := []struct {
+		dialect string
+		want    string
+	}{
+		{"", `SELECT "id", "name" FROM "users" WHERE "age" > 18 ORDER BY "name" ASC LIMIT 10 OFFSET 0`},
+		{"sqlite", `SELECT "id", "name" FROM "users" WHERE "age" > 18 ORDER BY "name" ASC LIMIT 10 OFFSET 0`},
+		{"postgres", `SELECT "id", "name" FROM "users" WHERE "age" > 18 ORDER BY "name" ASC LIMIT 10 OFFSET 0`},
+		{"mysql", "SELECT `id`, `name` FROM `users` WHERE `age` > 18 ORDER BY `name` ASC LIMIT 10 OFFSET 0"},
+	}
+	const rawURL = "data.sqlite;users;id,name,+name,age>18[10]"
+	want, err := Parse(rawURL)
+	if err != nil {
+		t.Fatalf("Parse(%q) error: %v", rawURL, err)
+	}
+	for _, tt := range tests {
+		dto, query, err := ParseWithSQL(rawURL, tt.dialect)
+		if err != nil {
+			t.Fatalf("ParseWithSQL(%q, %q) error: %v", rawURL, tt.dialect, err)
+		}
+		if !reflect.DeepEqual(dto, want) {
+			t.Errorf("ParseWithSQL(%q, %q) DTO = %+v, want %+v", rawURL, tt.dialect, dto, want)
+		}
+		if query != tt.want {
+			t.Errorf("ParseWithSQL(%q, %q) SQL = %q, want %q", rawURL, tt.dialect, query, tt.want)
+		}
+	}
+
+	if _, _, err := ParseWithSQL(rawURL, "oracle"); err == nil {
+		t.Error("ParseWithSQL with an unknown dialect: expected error")
+	}
+	if _, _, err := ParseWithSQL("%zz", "sqlite"); err == nil {
+		t.Error("ParseWithSQL with an invalid URL: expected error")
+	}
+}
+
+func TestPing(t *testing.T) {
+	if got := Ping(); got != "pong" {
+		t.Errorf("Ping() = %q, want %q", got, "pong")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	if got := Version(); got != "dev" {
+		t.Errorf("Version() = %q, want %q without ldflags", got, "dev")
+	}
+	defer func(v string) { version = v }(version)
+	version = "v1.2.3"
+	if got := Version(); got != "v1.2.3" {
+		t.Errorf("Version() = %q, want the injected %q", got, "v1.2.3")
+	}
+}
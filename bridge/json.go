@@ -0,0 +1,41 @@
+package bridge
+
+import (
+	"encoding/json"
+
+	"github.com/darianmavgo/banquet"
+	"github.com/darianmavgo/banquet/sqlite"
+)
+
+// ParseJSON parses rawURL and returns the BanquetDTO as a JSON string.
+// Failures are reported as {"error": "..."} so FFI and wasm callers always get JSON back.
+func ParseJSON(rawURL string) string {
+	result, err := Parse(rawURL)
+	if err != nil {
+		return errorJSON(err.Error())
+	}
+	return marshal(result)
+}
+
+// ComposeJSON parses rawURL and returns the composed SQLite query as {"sql": "..."}.
+// Failures are reported as {"error": "..."}.
+func ComposeJSON(rawURL string) string {
+	b, err := banquet.ParseBanquet(rawURL)
+	if err != nil {
+		return errorJSON(err.Error())
+	}
+	return marshal(map[string]string{"sql": sqlite.Compose(b)})
+}
+
+func marshal(v any) string {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return errorJSON("Failed to marshal result: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func errorJSON(msg string) string {
+	jsonBytes, _ := json.Marshal(map[string]string{"error": msg})
+	return string(jsonBytes)
+}
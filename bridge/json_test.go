@@ -0,0 +1,34 @@
+package bridge
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseJSON(t *testing.T) {
+	var dto BanquetDTO
+	if err := json.Unmarshal([]byte(ParseJSON("data.sqlite;users;id,name")), &dto); err != nil {
+		t.Fatalf("ParseJSON returned invalid JSON: %v", err)
+	}
+	if dto.Table != "users" || len(dto.Select) != 2 {
+		t.Errorf("ParseJSON = %+v, want users with 2 columns", dto)
+	}
+
+	var errObj map[string]string
+	if err := json.Unmarshal([]byte(ParseJSON("%zz")), &errObj); err != nil {
+		t.Fatalf("ParseJSON returned invalid JSON: %v", err)
+	}
+	if errObj["error"] == "" {
+		t.Errorf("Expected error field, got %v", errObj)
+	}
+}
+
+func TestComposeJSON(t *testing.T) {
+	var out map[string]string
+	if err := json.Unmarshal([]byte(ComposeJSON("data.sqlite;users;id[10]")), &out); err != nil {
+		t.Fatalf("ComposeJSON returned invalid JSON: %v", err)
+	}
+	if want := `SELECT "id" FROM "users" LIMIT 10 OFFSET 0`; out["sql"] != want {
+		t.Errorf("ComposeJSON sql = %q, want %q", out["sql"], want)
+	}
+}
@@ -15,4 +15,12 @@ type BanquetDTO struct {
 	DataSetPath   string
 	ColumnPath    string
 	OriginalURL   string
+	Auth          AuthDTO
+}
+
+// AuthDTO mirrors banquet.Auth.
+type AuthDTO struct {
+	Username string
+	Password string
+	Token    string
 }
@@ -0,0 +1,131 @@
+package banquet
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Builder assembles a Banquet from method calls instead of a hand written URL, e.g.
+//
+//	New().Dataset("data.sqlite").Table("users").Select("id", "name").
+//		Where("age", ">", 18).OrderBy("name", ASC).Limit(10).Build()
+//
+// Build writes the equivalent explicit URL (dataset;table;columns?query) and parses it, so
+// the result is exactly what ParseBanquet returns for that URL. The first invalid call is
+// reported by Build.
+type Builder struct {
+	dataset string
+	table   string
+	tokens  []string // column path tokens: selects, sorts and conditions
+	query   url.Values
+	err     error
+}
+
+// New starts an empty Builder.
+func New() *Builder {
+	return &Builder{query: url.Values{}}
+}
+
+// Dataset sets the dataset path, e.g. data/sales.sqlite.
+func (bd *Builder) Dataset(path string) *Builder {
+	bd.dataset = path
+	return bd
+}
+
+// Table sets the table tier, optionally schema qualified (public.users).
+func (bd *Builder) Table(name string) *Builder {
+	bd.table = name
+	return bd
+}
+
+// Select adds columns to the select list.
+func (bd *Builder) Select(columns ...string) *Builder {
+	for _, col := range columns {
+		if bd.checkColumn(col) {
+			bd.tokens = append(bd.tokens, col)
+		}
+	}
+	return bd
+}
+
+// Where adds a path condition comparing column with value using one of
+// =, !=, <, <=, >, >= or ~ (contains). Conditions are ANDed.
+func (bd *Builder) Where(column, op string, value any) *Builder {
+	switch Operator(op) {
+	case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe, CONTAINS:
+	default:
+		bd.fail(fmt.Errorf("banquet: builder: unsupported operator %q", op))
+		return bd
+	}
+	if bd.checkColumn(column) {
+		bd.tokens = append(bd.tokens, column+op+escapePathValue(fmt.Sprint(value)))
+	}
+	return bd
+}
+
+// OrderBy adds a sort on column; direction is ASC or DESC.
+func (bd *Builder) OrderBy(column, direction string) *Builder {
+	if direction != ASC && direction != DESC {
+		bd.fail(fmt.Errorf("banquet: builder: sort direction must be ASC or DESC, got %q", direction))
+		return bd
+	}
+	if bd.checkColumn(column) {
+		bd.tokens = append(bd.tokens, direction+column)
+	}
+	return bd
+}
+
+// Limit sets the LIMIT.
+func (bd *Builder) Limit(n int) *Builder {
+	bd.query.Set("limit", strconv.Itoa(n))
+	return bd
+}
+
+// Offset sets the OFFSET.
+func (bd *Builder) Offset(n int) *Builder {
+	bd.query.Set("offset", strconv.Itoa(n))
+	return bd
+}
+
+// Distinct selects distinct rows.
+func (bd *Builder) Distinct() *Builder {
+	bd.query.Set("distinct", "true")
+	return bd
+}
+
+// URL returns the Banquet URL the builder describes.
+func (bd *Builder) URL() string {
+	path := escapeTier(bd.dataset) + ";" + escapeTier(bd.table) + ";" + strings.Join(bd.tokens, ",")
+	u := url.URL{Path: path, RawQuery: bd.query.Encode()}
+	return u.String()
+}
+
+// Build parses URL into a Banquet, or returns the first error met while building.
+func (bd *Builder) Build() (*Banquet, error) {
+	if bd.err != nil {
+		return nil, bd.err
+	}
+	if bd.dataset == "" {
+		return nil, fmt.Errorf("banquet: builder: no dataset")
+	}
+	return ParseBanquet(bd.URL())
+}
+
+// checkColumn rejects names that would split into several column path tokens or read
+// back as a sort, exclusion, condition, slice (0:10) or page token (limit:5).
+func (bd *Builder) checkColumn(col string) bool {
+	if col == "" || strings.ContainsAny(col, ",/;[]|") || strings.ContainsAny(col[:1], "+-!") || hasOperator(col) ||
+		bareSlice.MatchString(col) || pageToken.MatchString(col) {
+		bd.fail(fmt.Errorf("banquet: builder: unsupported column name %q", col))
+		return false
+	}
+	return true
+}
+
+func (bd *Builder) fail(err error) {
+	if bd.err == nil {
+		bd.err = err
+	}
+}
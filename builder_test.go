@@ -0,0 +1,74 @@
+package banquet
+
+import (
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		builder *Builder
+		url     string
+	}{
+		{
+			New().Dataset("data.sqlite").Table("users").Select("id", "name").Where("age", ">", 18).OrderBy("name", ASC).Limit(10),
+			"data.sqlite;users;id,name,+name,age>18?limit=10",
+		},
+		{
+			New().Dataset("data/sales.csv").Select("amount").Where("region", "=", "North West").Where("total", ">=", 2.5).OrderBy("amount", DESC).Limit(5).Offset(10),
+			"data/sales.csv;;amount,-amount,region=North%20West,total>=2.5?limit=5&offset=10",
+		},
+		{
+			New().Dataset("data.sqlite").Table("public.users").Distinct(),
+			"data.sqlite;public.users?distinct=true",
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.builder.Build()
+		if err != nil {
+			t.Fatalf("Build() for %q failed: %v", tt.url, err)
+		}
+		want, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		if !sameClauses(got, want) {
+			t.Errorf("Build() = %+v\nParseBanquet(%q) = %+v", got, tt.url, want)
+		}
+	}
+}
+
+func TestBuilderEscapesValues(t *testing.T) {
+	b, err := New().Dataset("data.sqlite").Table("users").Where("name", "!=", "a,b|c/d").Build()
+	if err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	if want := `"name" != 'a,b|c/d'`; b.Where != want {
+		t.Errorf("Build() Where = %q, want %q", b.Where, want)
+	}
+
+	// A value that looks like a range is still compared for equality
+	b, err = New().Dataset("data.sqlite").Table("users").Where("name", "=", "1..5").Build()
+	if err != nil {
+		t.Fatalf("Build() failed: %v", err)
+	}
+	if want := `"name" = '1..5'`; b.Where != want {
+		t.Errorf("Build() Where = %q, want %q", b.Where, want)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	for name, bd := range map[string]*Builder{
+		"no dataset":    New().Table("users"),
+		"bad operator":  New().Dataset("data.sqlite").Where("age", "LIKE", "x"),
+		"bad direction": New().Dataset("data.sqlite").OrderBy("age", "ASC"),
+		"bad column":    New().Dataset("data.sqlite").Select("id,name"),
+		"sort column":   New().Dataset("data.sqlite").Select("-id"),
+		"slice column":  New().Dataset("data.sqlite").Select("0:10"),
+		"page column":   New().Dataset("data.sqlite").Select("limit:5"),
+		"slice where":   New().Dataset("data.sqlite").Where("0:10", "=", 1),
+	} {
+		if _, err := bd.Build(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
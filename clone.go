@@ -0,0 +1,95 @@
+package banquet
+
+import (
+	"net/url"
+	"slices"
+)
+
+// Clone returns a deep copy of b. The embedded URL, the From subquery and the Select, Exclude, Sorts, Conditions, Warnings and Extra slices are copied,
+// so the clone can be modified (e.g. a different Offset) without affecting b.
+func (b *Banquet) Clone() *Banquet {
+	if b == nil {
+		return nil
+	}
+	c := *b
+	if b.URL != nil {
+		u := *b.URL
+		if b.User != nil {
+			// url.Userinfo is immutable, but copy it so the clone shares no pointers
+			user := *b.User
+			u.User = &user
+		}
+		c.URL = &u
+	}
+	c.Select = slices.Clone(b.Select)
+	c.Sorts = slices.Clone(b.Sorts)
+	c.Exclude = slices.Clone(b.Exclude)
+	c.Conditions = cloneConditions(b.Conditions)
+	c.Warnings = slices.Clone(b.Warnings)
+	c.From = b.From.Clone()
+	c.Extra = slices.Clone(b.Extra)
+	return &c
+}
+
+// cloneConditions deep copies conds including nested Values, IN tuples and Or groups.
+func cloneConditions(conds []Condition) []Condition {
+	if conds == nil {
+		return nil
+	}
+	out := make([]Condition, len(conds))
+	for i, cond := range conds {
+		cond.Values = slices.Clone(cond.Values)
+		cond.Columns = slices.Clone(cond.Columns)
+		if cond.Tuples != nil {
+			tuples := make([][]string, len(cond.Tuples))
+			for j, tuple := range cond.Tuples {
+				tuples[j] = slices.Clone(tuple)
+			}
+			cond.Tuples = tuples
+		}
+		cond.Or = cloneConditions(cond.Or)
+		out[i] = cond
+	}
+	return out
+}
+
+// Equal reports whether b and other describe the same query. It compares the URL and the
+// parsed clauses and ignores internal bookkeeping such as the cleaned raw URL.
+func (b *Banquet) Equal(other *Banquet) bool {
+	if b == nil || other == nil {
+		return b == other
+	}
+	return urlString(b.URL) == urlString(other.URL) &&
+		b.Where == other.Where &&
+		b.Table == other.Table &&
+		b.TableSource == other.TableSource &&
+		b.Schema == other.Schema &&
+		slices.Equal(b.Select, other.Select) &&
+		b.SelectAll == other.SelectAll &&
+		slices.Equal(b.Exclude, other.Exclude) &&
+		b.Distinct == other.Distinct &&
+		b.SortDirection == other.SortDirection &&
+		b.Limit == other.Limit &&
+		b.Offset == other.Offset &&
+		b.GroupBy == other.GroupBy &&
+		b.Having == other.Having &&
+		b.OrderBy == other.OrderBy &&
+		b.RawSQL == other.RawSQL &&
+		slices.Equal(b.Sorts, other.Sorts) &&
+		b.DataSetPath == other.DataSetPath &&
+		b.From.Equal(other.From) &&
+		slices.Equal(b.Extra, other.Extra) &&
+		b.ColumnPath == other.ColumnPath &&
+		b.OutputFormat == other.OutputFormat &&
+		b.Auth == other.Auth &&
+		b.FromEnd == other.FromEnd &&
+		b.sliceStart == other.sliceStart &&
+		b.sliceEnd == other.sliceEnd
+}
+
+func urlString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
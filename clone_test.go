@@ -0,0 +1,55 @@
+package banquet
+
+import "testing"
+
+func TestClone(t *testing.T) {
+	b, err := ParseBanquet("gs://matrix@bucket/data.sqlite;users;id,name,-age?limit=10&offset=20")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+
+	c := b.Clone()
+	if !c.Equal(b) {
+		t.Fatalf("Expected clone to equal source")
+	}
+
+	c.Offset = "30"
+	c.Select[0] = "email"
+	c.Sorts[0].Direction = "ASC"
+	c.Host = "other"
+	c.RawQuery = "limit=10&offset=30"
+
+	if b.Offset != "20" {
+		t.Errorf("Source Offset changed to %q", b.Offset)
+	}
+	if b.Select[0] != "id" {
+		t.Errorf("Source Select changed to %v", b.Select)
+	}
+	if b.Sorts[0].Direction != "DESC" {
+		t.Errorf("Source Sorts changed to %v", b.Sorts)
+	}
+	if b.Host != "bucket" || b.RawQuery != "limit=10&offset=20" {
+		t.Errorf("Source URL changed to %s", b.String())
+	}
+	if c.Equal(b) {
+		t.Errorf("Expected modified clone to differ from source")
+	}
+}
+
+func TestEqualIgnoresRawURL(t *testing.T) {
+	a, err := ParseBanquet("/data.sqlite;users;id")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	b, err := ParseBanquet("data.sqlite;users;id")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	b.rawurl = "something else"
+	if !a.Equal(b) {
+		t.Errorf("Expected Banquets differing only in rawurl to be equal")
+	}
+	if a.Equal(nil) {
+		t.Errorf("Expected Banquet not to equal nil")
+	}
+}
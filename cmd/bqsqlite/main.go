@@ -1,26 +1,140 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/darianmavgo/banquet"
+	"github.com/darianmavgo/banquet/bigquery"
+	"github.com/darianmavgo/banquet/bridge"
+	"github.com/darianmavgo/banquet/mysql"
+	"github.com/darianmavgo/banquet/postgres"
 	"github.com/darianmavgo/banquet/sqlite"
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: bqsqlite <url>")
-		os.Exit(1)
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// options are the flags that shape how each URL is translated.
+type options struct {
+	dialect       string
+	parameterized bool
+	json          bool
+}
+
+// run is main without the process exit so it can be tested.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bqsqlite", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var opts options
+	fs.StringVar(&opts.dialect, "dialect", "sqlite", "SQL dialect: sqlite, postgres, mysql, bigquery")
+	fs.BoolVar(&opts.parameterized, "parameterized", false, "print the query with bound args")
+	fs.BoolVar(&opts.json, "json", false, "print the parsed fields as JSON instead of SQL")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: bqsqlite [-dialect name] [-parameterized] [-json] <url | ->")
+		fmt.Fprintln(stderr, "With no url or \"-\", URLs are read from stdin one per line.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 || fs.Arg(0) == "-" {
+		return runLines(stdin, stdout, stderr, opts)
+	}
+	if err := translate(stdout, fs.Arg(0), opts); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runLines translates each non-blank line of r, reporting failures with their line number.
+// Every line is attempted; the exit code is 1 if any of them failed.
+func runLines(r io.Reader, stdout, stderr io.Writer, opts options) int {
+	code := 0
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		rawURL := strings.TrimSpace(scanner.Text())
+		if rawURL == "" {
+			continue
+		}
+		if err := translate(stdout, rawURL, opts); err != nil {
+			fmt.Fprintf(stderr, "line %d: %v\n", line, err)
+			code = 1
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(stderr, "Error reading stdin: %v\n", err)
+		return 1
+	}
+	return code
+}
+
+// translate parses rawURL and writes its SQL, followed by the bound args when parameterized.
+// In JSON mode it writes the bridge.BanquetDTO instead.
+func translate(w io.Writer, rawURL string, opts options) error {
+	if opts.json {
+		dto, err := bridge.Parse(rawURL)
+		if err != nil {
+			return fmt.Errorf("parsing URL: %w", err)
+		}
+		out, err := json.MarshalIndent(dto, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(out))
+		return nil
 	}
 
-	rawURL := os.Args[1]
 	bq, err := banquet.ParseBanquet(rawURL)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing URL: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("parsing URL: %w", err)
 	}
+	query, queryArgs, err := compose(opts.dialect, bq, opts.parameterized)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, query)
+	if opts.parameterized {
+		fmt.Fprintf(w, "%v\n", queryArgs)
+	}
+	return nil
+}
 
-	query := sqlite.Compose(bq)
-	fmt.Println(query)
+// compose renders bq with the composer for dialect, using the bound-args variant when parameterized.
+func compose(dialect string, bq *banquet.Banquet, parameterized bool) (string, []any, error) {
+	switch dialect {
+	case "sqlite":
+		if parameterized {
+			query, args := sqlite.ComposeArgs(bq)
+			return query, args, nil
+		}
+		return sqlite.Compose(bq), nil, nil
+	case "postgres":
+		if parameterized {
+			query, args := postgres.ComposeArgs(bq)
+			return query, args, nil
+		}
+		return postgres.Compose(bq), nil, nil
+	case "mysql":
+		if parameterized {
+			query, args := mysql.ComposeArgs(bq)
+			return query, args, nil
+		}
+		return mysql.Compose(bq), nil, nil
+	case "bigquery":
+		if parameterized {
+			query, args := bigquery.ComposeArgs(bq)
+			return query, args, nil
+		}
+		return bigquery.Compose(bq), nil, nil
+	}
+	return "", nil, fmt.Errorf("unknown dialect %q", dialect)
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/darianmavgo/banquet/bridge"
+)
+
+func TestRunDialects(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"data.sqlite;users;id,name"}, "SELECT \"id\", \"name\" FROM \"users\"\n"},
+		{[]string{"-dialect", "postgres", "data.sqlite;users;id,name"}, "SELECT \"id\", \"name\" FROM \"users\"\n"},
+		{[]string{"-dialect", "mysql", "data.sqlite;users;id,name"}, "SELECT `id`, `name` FROM `users`\n"},
+		{[]string{"-dialect", "bigquery", "gs://project/dataset;users;id"}, "SELECT `id` FROM `project.dataset.users`\n"},
+		{[]string{"-parameterized", "data.sqlite;users[10:20]"}, "SELECT * FROM \"users\" LIMIT ? OFFSET ?\n[10 10]\n"},
+		{[]string{"-dialect", "postgres", "-parameterized", "data.sqlite;users[10]"}, "SELECT * FROM \"users\" LIMIT $1 OFFSET $2\n[10 0]\n"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run(tt.args, nil, &stdout, &stderr); code != 0 {
+			t.Fatalf("run(%v) exit %d: %s", tt.args, code, stderr.String())
+		}
+		if stdout.String() != tt.expected {
+			t.Errorf("run(%v) = %q, want %q", tt.args, stdout.String(), tt.expected)
+		}
+	}
+}
+
+func TestRunUnknownDialect(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-dialect", "oracle", "data.sqlite;users"}, nil, &stdout, &stderr); code == 0 {
+		t.Errorf("Expected non-zero exit for unknown dialect")
+	}
+	if !strings.Contains(stderr.String(), "oracle") {
+		t.Errorf("Expected error to name the dialect, got %q", stderr.String())
+	}
+}
+
+func TestRunStdin(t *testing.T) {
+	input := "data.sqlite;users;id\n\n  \ndata.sqlite;orders[5]\n%zz\n"
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-"}, strings.NewReader(input), &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit 1 when a line fails, got %d", code)
+	}
+	expected := "SELECT \"id\" FROM \"users\"\nSELECT * FROM \"orders\" LIMIT 5 OFFSET 0\n"
+	if stdout.String() != expected {
+		t.Errorf("stdout = %q, want %q", stdout.String(), expected)
+	}
+	if !strings.HasPrefix(stderr.String(), "line 5:") {
+		t.Errorf("Expected error annotated with line 5, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := run(nil, strings.NewReader("data.sqlite;users\n"), &stdout, &stderr); code != 0 {
+		t.Fatalf("run with no args exit %d: %s", code, stderr.String())
+	}
+	if stdout.String() != "SELECT * FROM \"users\"\n" {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-json", "data.sqlite;users;id,name?where=age>18"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exit %d: %s", code, stderr.String())
+	}
+	var dto bridge.BanquetDTO
+	if err := json.Unmarshal(stdout.Bytes(), &dto); err != nil {
+		t.Fatalf("Output is not JSON: %v\n%s", err, stdout.String())
+	}
+	if dto.Table != "users" {
+		t.Errorf("Table = %q, want %q", dto.Table, "users")
+	}
+	if len(dto.Select) != 2 || dto.Select[0] != "id" || dto.Select[1] != "name" {
+		t.Errorf("Select = %v, want [id name]", dto.Select)
+	}
+	if dto.Where != "age>18" {
+		t.Errorf("Where = %q, want %q", dto.Where, "age>18")
+	}
+}
@@ -6,7 +6,6 @@ package main
 import "C"
 
 import (
-	"encoding/json"
 	"unsafe"
 
 	"github.com/darianmavgo/banquet/bridge"
@@ -20,27 +19,27 @@ func main() {}
 //
 //export BanquetParse
 func BanquetParse(url *C.char) *C.char {
-	goStr := C.GoString(url)
-
-	result, err := bridge.Parse(goStr)
-	if err != nil {
-		// Return error as JSON object with error field
-		errObj := map[string]string{"error": err.Error()}
-		jsonBytes, _ := json.Marshal(errObj)
-		return C.CString(string(jsonBytes))
-	}
-
-	jsonBytes, err := json.Marshal(result)
-	if err != nil {
-		errObj := map[string]string{"error": "Failed to marshal result: " + err.Error()}
-		jsonBytes, _ := json.Marshal(errObj)
-		return C.CString(string(jsonBytes))
-	}
-
-	return C.CString(string(jsonBytes))
+	return C.CString(bridge.ParseJSON(C.GoString(url)))
 }
 
-// FreeString frees the C string returned by BanquetParse.
+// BanquetPing returns "pong", so a host can check the library loaded without parsing a URL.
+// The caller is responsible for freeing the returned C string using FreeString.
+//
+//export BanquetPing
+func BanquetPing() *C.char {
+	return C.CString(bridge.Ping())
+}
+
+// BanquetVersion returns the build version, set with
+// -ldflags "-X github.com/darianmavgo/banquet/bridge.version=v1.2.3".
+// The caller is responsible for freeing the returned C string using FreeString.
+//
+//export BanquetVersion
+func BanquetVersion() *C.char {
+	return C.CString(bridge.Version())
+}
+
+// FreeString frees a C string returned by BanquetParse, BanquetPing or BanquetVersion.
 //
 //export FreeString
 func FreeString(str *C.char) {
@@ -0,0 +1,34 @@
+//go:build js && wasm
+
+// Command wasmbanquet exposes the banquet parser to JavaScript.
+// Build with: GOOS=js GOARCH=wasm go build -o banquet.wasm ./cmd/wasmbanquet
+package main
+
+import (
+	"syscall/js"
+
+	"github.com/darianmavgo/banquet/bridge"
+)
+
+func main() {
+	js.Global().Set("banquetParse", js.FuncOf(banquetParse))
+	js.Global().Set("banquetCompose", js.FuncOf(banquetCompose))
+	// Keep the Go runtime alive so the functions stay callable.
+	select {}
+}
+
+// banquetParse(url) returns the parsed BanquetDTO as a JSON string, like BanquetParse in libbanquet.
+func banquetParse(this js.Value, args []js.Value) any {
+	if len(args) < 1 {
+		return bridge.ParseJSON("")
+	}
+	return bridge.ParseJSON(args[0].String())
+}
+
+// banquetCompose(url) returns {"sql": "..."} with the SQLite query for url.
+func banquetCompose(this js.Value, args []js.Value) any {
+	if len(args) < 1 {
+		return bridge.ComposeJSON("")
+	}
+	return bridge.ComposeJSON(args[0].String())
+}
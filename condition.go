@@ -0,0 +1,515 @@
+package banquet
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operator is the comparison of a Condition. The values match the SQLite spelling;
+// dialects that differ map them when rendering.
+type Operator string
+
+const (
+	OpEq      Operator = "="
+	OpNe      Operator = "!="
+	OpLt      Operator = "<"
+	OpLe      Operator = "<="
+	OpGt      Operator = ">"
+	OpGe      Operator = ">="
+	OpBetween Operator = "BETWEEN"
+	OpIn      Operator = "IN"
+	OpLike    Operator = "LIKE" // Contains, written name~smith; see Dialect.Like.
+)
+
+// CONTAINS is the column path operator matching values that contain the text, e.g. name~smith.
+const CONTAINS = "~"
+
+// Condition is a comparison parsed from the column path, e.g. status!=active.
+// A Condition with Or set is a group of alternatives (a=1|b=2) and has no Column of its own.
+// A tuple IN, (region,product)=[(west,widget),(east,gadget)], sets Columns and Tuples instead.
+type Condition struct {
+	Column    string
+	Operator  Operator
+	Value     string      // Decoded value; empty for BETWEEN.
+	Values    []string    // Lower and upper bound for BETWEEN.
+	Columns   []string    // Column tuple for IN.
+	Tuples    [][]string  // Decoded value tuples for IN, one value per column; numbers are rendered unquoted.
+	IsNumeric bool        // Value (or both Values) parse as numbers and are rendered unquoted.
+	IsColumn  bool        // Value names a column (written @col) and is rendered as an identifier.
+	Or        []Condition // Alternatives joined with OR.
+}
+
+// String renders c as a SQLite fragment with a double-quoted column, as used for Banquet.Where.
+func (c Condition) String() string {
+	return sqliteRenderer.Condition(c)
+}
+
+// sqliteRenderer renders the Where convenience string.
+var sqliteRenderer = Renderer{Column: quoteIdentifier}
+
+// quoteIdentifier wraps s in double quotes, escaping embedded double quotes.
+func quoteIdentifier(s string) string {
+	return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+}
+
+// Renderer turns structured conditions into SQL for a dialect.
+// The zero value leaves columns bare and spells operators as in SQLite.
+type Renderer struct {
+	Column   func(string) string   // Quotes a column name; nil leaves it bare.
+	Operator func(Operator) string // Spells an operator; nil uses the Operator value.
+	String   func(string) string   // Quotes a string literal; nil doubles single quotes as standard SQL does.
+	// NullsOrdering renders OrderTerm.Nulls as NULLS FIRST/LAST. Without it the order is
+	// emulated with a leading CASE WHEN col IS NULL sort key.
+	NullsOrdering bool
+	// Types overrides the guess of whether a value is a number for the columns it lists,
+	// keyed by column name as written in the URL; see ColumnType.
+	Types map[string]ColumnType
+}
+
+// ColumnType tells Renderer how to write the values compared against a column.
+type ColumnType int
+
+const (
+	// TypeUnknown quotes values unless they look like numbers, see Condition.IsNumeric.
+	TypeUnknown ColumnType = iota
+	// TypeText always quotes values, so zip=12345 compares against '12345'.
+	TypeText
+	// TypeNumeric leaves any decimal number bare, including 007. Anything else is quoted.
+	TypeNumeric
+	// TypeBoolean writes true and false (in any case) as TRUE and FALSE and leaves 1 and 0
+	// bare. Anything else is quoted.
+	TypeBoolean
+)
+
+// Dialect is the SQL spelling of one engine as far as rendering conditions is concerned.
+// Each composer package exports its own, e.g. postgres.Dialect.
+type Dialect interface {
+	QuoteIdentifier(name string) string
+	NotEqual() string    // "!=" or the ANSI "<>".
+	NullsOrdering() bool // Whether ORDER BY accepts NULLS FIRST/LAST.
+	Like() string        // The case-insensitive pattern match for OpLike, "LIKE" or "ILIKE".
+	// QuoteString quotes a string literal, escaping it as the engine requires; MySQL, for
+	// one, reads a backslash as an escape.
+	QuoteString(s string) string
+}
+
+// RendererFor returns a Renderer that quotes columns and spells operators as d does.
+func RendererFor(d Dialect) Renderer {
+	return Renderer{
+		Column: d.QuoteIdentifier,
+		Operator: func(op Operator) string {
+			switch op {
+			case OpNe:
+				return d.NotEqual()
+			case OpLike:
+				return d.Like()
+			}
+			return string(op)
+		},
+		NullsOrdering: d.NullsOrdering(),
+		String:        d.QuoteString,
+	}
+}
+
+// OrderBy renders sorts as a comma separated ORDER BY list, e.g. "name" ASC NULLS FIRST.
+// Columns wrapped in ScalarFunctions are quoted as QuoteColumn does, and positions (+2) stay
+// bare. The CASE WHEN emulation of Nulls needs a column, so it is skipped for positions.
+func (r Renderer) OrderBy(sorts []OrderTerm) string {
+	quote := r.Column
+	if quote == nil {
+		quote = func(s string) string { return s }
+	}
+	var terms []string
+	for _, sort := range sorts {
+		col := QuoteColumn(sort.Column, quote)
+		if sort.IsPosition() {
+			col = sort.Column
+		}
+		term := col
+		if sort.Direction != "" {
+			term += " " + sort.Direction
+		}
+		switch {
+		case sort.Nulls == "":
+		case r.NullsOrdering:
+			term += " NULLS " + sort.Nulls
+		case sort.IsPosition():
+		case sort.Nulls == "FIRST":
+			terms = append(terms, "CASE WHEN "+col+" IS NULL THEN 0 ELSE 1 END")
+		default:
+			terms = append(terms, "CASE WHEN "+col+" IS NULL THEN 1 ELSE 0 END")
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, ", ")
+}
+
+// Condition renders c, parenthesizing OR groups and quoting non-numeric values.
+// Column references (IsColumn) are quoted like the column itself.
+func (r Renderer) Condition(c Condition) string {
+	if len(c.Or) > 0 {
+		alts := make([]string, len(c.Or))
+		for i, alt := range c.Or {
+			alts[i] = r.Condition(alt)
+		}
+		return "(" + strings.Join(alts, " OR ") + ")"
+	}
+	col := c.Column
+	if r.Column != nil {
+		col = r.Column(col)
+	}
+	op := string(c.Operator)
+	if r.Operator != nil {
+		op = r.Operator(c.Operator)
+	}
+	if c.Operator == OpLike {
+		return fmt.Sprintf("%s %s %s", col, op, r.containsPattern(c.Value))
+	}
+	if c.Operator == OpBetween && len(c.Values) == 2 {
+		return fmt.Sprintf("%s %s %s AND %s", col, op, r.value(c.Column, c.Values[0], c.IsNumeric), r.value(c.Column, c.Values[1], c.IsNumeric))
+	}
+	if c.Operator == OpIn && len(c.Columns) > 0 {
+		cols := make([]string, len(c.Columns))
+		for i, name := range c.Columns {
+			cols[i] = name
+			if r.Column != nil {
+				cols[i] = r.Column(name)
+			}
+		}
+		tuples := make([]string, len(c.Tuples))
+		for i, tuple := range c.Tuples {
+			vals := make([]string, len(tuple))
+			for j, val := range tuple {
+				column := ""
+				if j < len(c.Columns) {
+					column = c.Columns[j]
+				}
+				vals[j] = r.value(column, val, isNumeric(val))
+			}
+			tuples[i] = "(" + strings.Join(vals, ",") + ")"
+		}
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(cols, ","), op, strings.Join(tuples, ","))
+	}
+	if c.IsColumn {
+		rhs := c.Value
+		if r.Column != nil {
+			rhs = r.Column(rhs)
+		}
+		return fmt.Sprintf("%s %s %s", col, op, rhs)
+	}
+	return fmt.Sprintf("%s %s %s", col, op, r.value(c.Column, c.Value, c.IsNumeric))
+}
+
+// decimalLiteral matches the values a TypeNumeric column leaves bare.
+var decimalLiteral = regexp.MustCompile(`^[-+]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+
+// value renders val compared against column, following r.Types over the numeric guess.
+func (r Renderer) value(column, val string, numeric bool) string {
+	switch r.Types[column] {
+	case TypeText:
+		return r.literal(val, false)
+	case TypeNumeric:
+		return r.literal(val, decimalLiteral.MatchString(val))
+	case TypeBoolean:
+		switch strings.ToLower(val) {
+		case "true":
+			return "TRUE"
+		case "false":
+			return "FALSE"
+		}
+		return r.literal(val, val == "1" || val == "0")
+	}
+	return r.literal(val, numeric)
+}
+
+// Where renders the full WHERE expression of b: the where query param as parsed (after
+// StripComments and QuoteInLists) ANDed with b.Conditions. A Banquet without Conditions (e.g.
+// built by hand) falls back to b.Where as is. Parsing sets b.Where to the same expression
+// rendered for SQLite; a filter a caller adds to b.Where afterwards
+// (b.Where += " AND tenant_id = 7") or a b.Where replaced outright is ANDed in.
+func (r Renderer) Where(b *Banquet) string {
+	if len(b.Conditions) == 0 {
+		return b.Where
+	}
+	var conds, parsed []string
+	if b.queryWhere != "" {
+		conds = append(conds, b.queryWhere)
+		parsed = append(parsed, b.queryWhere)
+	}
+	for _, cond := range b.Conditions {
+		conds = append(conds, r.Condition(cond))
+		parsed = append(parsed, sqliteRenderer.Condition(cond))
+	}
+	where := strings.Join(conds, " AND ")
+	own := b.parsedWhere
+	if own == "" {
+		own = strings.Join(parsed, " AND ")
+	}
+	switch {
+	case b.Where == "" || b.Where == own:
+	case strings.HasPrefix(b.Where, own+" AND "):
+		where += b.Where[len(own):]
+	default:
+		where += " AND (" + b.Where + ")"
+	}
+	return where
+}
+
+// literal renders a condition value, quoting it with r.String unless numeric.
+func (r Renderer) literal(val string, numeric bool) string {
+	if numeric || r.String == nil {
+		return literal(val, numeric)
+	}
+	return r.String(val)
+}
+
+// literal renders a condition value, single-quoting and escaping it unless numeric.
+func literal(val string, numeric bool) string {
+	if numeric {
+		return val
+	}
+	return "'" + strings.ReplaceAll(val, "'", "''") + "'"
+}
+
+// likeEscaper escapes the LIKE wildcards with !, which needs no backslash escaping in any dialect.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// containsPattern renders val as a quoted %val% pattern. Wildcards in val match literally,
+// with an ESCAPE clause when any had to be escaped.
+func (r Renderer) containsPattern(val string) string {
+	escaped := likeEscaper.Replace(val)
+	pattern := r.literal("%"+escaped+"%", false)
+	if escaped != val {
+		pattern += " ESCAPE '!'"
+	}
+	return pattern
+}
+
+// numberLiteral matches plain decimal numbers. ParseFloat alone also takes 007, 0x1p3, Inf
+// and NaN, which are more likely text IDs or words than numbers.
+var numberLiteral = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)?(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// isNumeric reports whether every value is a plain decimal number. A value with a
+// significant leading zero (zip=00123, id=007) is text, so it keeps its quotes and zeros.
+func isNumeric(values ...string) bool {
+	for _, val := range values {
+		if !numberLiteral.MatchString(val) {
+			return false
+		}
+		if _, err := strconv.ParseFloat(val, 64); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// pathToken renders c back into column path notation, the inverse of parseConditionGroup.
+func (c Condition) pathToken() string {
+	if len(c.Or) > 0 {
+		alts := make([]string, len(c.Or))
+		for i, alt := range c.Or {
+			alts[i] = alt.pathToken()
+		}
+		return strings.Join(alts, OR)
+	}
+	column := escapeComma(c.Column)
+	switch {
+	case c.Operator == OpBetween && len(c.Values) == 2:
+		return column + "=" + escapePathValue(c.Values[0]) + RANGE + escapePathValue(c.Values[1])
+	case c.Operator == OpIn && len(c.Columns) > 0:
+		tuples := make([]string, len(c.Tuples))
+		for i, tuple := range c.Tuples {
+			vals := make([]string, len(tuple))
+			for j, val := range tuple {
+				vals[j] = tupleValueEscaper.Replace(escapePathValue(val))
+			}
+			tuples[i] = "(" + strings.Join(vals, ",") + ")"
+		}
+		return "(" + strings.Join(c.Columns, ",") + ")=[" + strings.Join(tuples, ",") + "]"
+	case c.IsColumn:
+		return column + string(c.Operator) + "@" + c.Value
+	case c.Operator == OpLike:
+		return column + CONTAINS + escapePathValue(c.Value)
+	}
+	return column + string(c.Operator) + escapePathValue(c.Value)
+}
+
+// pathValueEscaper %-escapes the characters that delimit tokens in a column path, plus
+// those parseCondition would decode, and the .. of a range so 1..5 stays one value.
+var pathValueEscaper = strings.NewReplacer(
+	"%", "%25", "+", "%2B", " ", "%20", ",", "%2C", "/", "%2F", "|", "%7C",
+	";", "%3B", "?", "%3F", "#", "%23", "&", "%26", "[", "%5B", "]", "%5D", "@", "%40",
+	RANGE, ".%2E",
+)
+
+// tupleValueEscaper additionally escapes the parentheses that delimit IN tuples.
+var tupleValueEscaper = strings.NewReplacer("(", "%28", ")", "%29")
+
+// escapePathValue escapes a decoded condition value for use in a column path.
+func escapePathValue(val string) string {
+	return pathValueEscaper.Replace(val)
+}
+
+// ParsePath breaks a column path into its selected columns, conditions, sorts and slice,
+// without parsing the dataset or query. Selects defaults to * like ParseSelect, and Limit
+// and Offset are empty when the path holds no slice.
+func ParsePath(columnPath string) (Selects []string, Conditions []Condition, Sorts []OrderTerm, Limit, Offset string) {
+	pc := scanColumnPath(columnPath)
+	Limit, Offset = parseSlice(columnPath)
+	return pc.selectList(), pc.conditions, pc.sorts, Limit, Offset
+}
+
+// parseConditionGroup parses a token of |-separated conditions, grouping alternatives under Or.
+// An alternative that isn't a condition, such as b in note=a|b, is dropped and returned as a
+// *ValidationError for the Conditions field alongside the rest of the group.
+func parseConditionGroup(token string) (Condition, bool, error) {
+	var alts []Condition
+	var err error
+	for _, alt := range strings.Split(token, OR) {
+		if cond, ok := parseCondition(alt); ok {
+			alts = append(alts, cond)
+		} else if err == nil {
+			err = &ValidationError{Field: "Conditions", Value: token, Reason: fmt.Sprintf("alternative %q has no operator", alt)}
+		}
+	}
+	switch len(alts) {
+	case 0:
+		return Condition{}, false, err
+	case 1:
+		return alts[0], true, err
+	}
+	return Condition{Or: alts}, true, err
+}
+
+// isTupleIn reports whether segment holds a tuple IN condition, (a,b)=[(1,2)].
+func isTupleIn(segment string) bool {
+	return strings.Contains(segment, "(") && strings.Contains(segment, "=[")
+}
+
+// parseTupleIn parses a (col,col)=[(val,val),...] token into an OpIn condition. Values are
+// decoded like other condition values. Unbalanced parentheses or brackets, or a tuple whose
+// length doesn't match the columns, return a *ValidationError for the Conditions field.
+func parseTupleIn(token string) (Condition, error) {
+	invalid := func(reason string) (Condition, error) {
+		return Condition{}, &ValidationError{Field: "Conditions", Value: token, Reason: reason}
+	}
+	if !balanced(token) {
+		return invalid("unbalanced parentheses")
+	}
+	lhs, rhs, ok := strings.Cut(strings.TrimSpace(token), "=")
+	lhs, rhs = strings.TrimSpace(lhs), strings.TrimSpace(rhs)
+	if !ok || !strings.HasPrefix(lhs, "(") || !strings.HasSuffix(lhs, ")") || !strings.HasPrefix(rhs, "[") || !strings.HasSuffix(rhs, "]") {
+		return invalid("want (col,col)=[(val,val),...]")
+	}
+	c := Condition{Operator: OpIn}
+	for _, col := range strings.Split(lhs[1:len(lhs)-1], ",") {
+		if col = strings.TrimSpace(col); col == "" {
+			return invalid("empty column")
+		}
+		c.Columns = append(c.Columns, col)
+	}
+	list := strings.TrimSpace(rhs[1 : len(rhs)-1])
+	for list != "" {
+		end := strings.IndexByte(list, ')')
+		if !strings.HasPrefix(list, "(") || end == -1 {
+			return invalid("want parenthesized value tuples")
+		}
+		var tuple []string
+		for _, val := range strings.Split(list[1:end], ",") {
+			val = strings.TrimSpace(val)
+			if decoded, err := url.QueryUnescape(val); err == nil {
+				val = decoded
+			}
+			tuple = append(tuple, val)
+		}
+		if len(tuple) != len(c.Columns) {
+			return invalid(fmt.Sprintf("tuple of %d values for %d columns", len(tuple), len(c.Columns)))
+		}
+		c.Tuples = append(c.Tuples, tuple)
+		list = strings.TrimSpace(list[end+1:])
+		if rest, ok := strings.CutPrefix(list, ","); ok {
+			list = strings.TrimSpace(rest)
+			if list == "" {
+				return invalid("trailing comma")
+			}
+		} else if list != "" {
+			return invalid("want a comma between tuples")
+		}
+	}
+	if len(c.Tuples) == 0 {
+		return invalid("empty IN list")
+	}
+	return c, nil
+}
+
+// decodeValue URL decodes a condition value, keeping it as written when it doesn't decode.
+func decodeValue(val string) string {
+	if decoded, err := url.QueryUnescape(val); err == nil {
+		return decoded
+	}
+	return val
+}
+
+// balanced reports whether the parentheses and brackets in s are balanced and properly nested.
+func balanced(s string) bool {
+	var stack []byte
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[':
+			stack = append(stack, s[i])
+		case ')', ']':
+			open := byte('(')
+			if s[i] == ']' {
+				open = '['
+			}
+			if len(stack) == 0 || stack[len(stack)-1] != open {
+				return false
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return len(stack) == 0
+}
+
+// parseCondition parses a col<op>val token, decoding the value and expanding ranges.
+func parseCondition(token string) (Condition, bool) {
+	idx, op := findOperator(token)
+	if idx == -1 {
+		return Condition{}, false
+	}
+	col := strings.TrimSpace(token[:idx])
+	val := strings.TrimSpace(token[idx+len(op):])
+	if col == "" {
+		return Condition{}, false
+	}
+
+	// A leading @ compares against another column, e.g. ship_date>@order_date
+	if ref, ok := strings.CutPrefix(val, "@"); ok && ref != "" && op != CONTAINS {
+		return Condition{Column: col, Operator: Operator(op), Value: ref, IsColumn: true}, true
+	}
+
+	// Ranges: col=lo..hi, col=lo.. and col=..hi. The .. is found before decoding, so an
+	// escaped .%2E is a literal value.
+	if op == "=" && strings.Contains(val, RANGE) {
+		bounds := strings.SplitN(val, RANGE, 2)
+		lo, hi := strings.TrimSpace(decodeValue(bounds[0])), strings.TrimSpace(decodeValue(bounds[1]))
+		switch {
+		case lo != "" && hi != "":
+			return Condition{Column: col, Operator: OpBetween, Values: []string{lo, hi}, IsNumeric: isNumeric(lo, hi)}, true
+		case lo != "":
+			return Condition{Column: col, Operator: OpGe, Value: lo, IsNumeric: isNumeric(lo)}, true
+		case hi != "":
+			return Condition{Column: col, Operator: OpLe, Value: hi, IsNumeric: isNumeric(hi)}, true
+		}
+	}
+
+	val = decodeValue(val)
+	if op == CONTAINS {
+		return Condition{Column: col, Operator: OpLike, Value: val}, true
+	}
+
+	return Condition{Column: col, Operator: Operator(op), Value: val, IsNumeric: isNumeric(val)}, true
+}
@@ -0,0 +1,194 @@
+package banquet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	selects, conditions, sorts, limit, offset := ParsePath("id,name,-age,status!=active,total=50..500,kind=a|kind=b[10:30]")
+
+	if want := []string{"id", "name"}; !reflect.DeepEqual(selects, want) {
+		t.Errorf("Selects = %v, want %v", selects, want)
+	}
+	wantConds := []Condition{
+		{Column: "status", Operator: "!=", Value: "active"},
+		{Column: "total", Operator: OpBetween, Values: []string{"50", "500"}, IsNumeric: true},
+		{Or: []Condition{
+			{Column: "kind", Operator: "=", Value: "a"},
+			{Column: "kind", Operator: "=", Value: "b"},
+		}},
+	}
+	if !reflect.DeepEqual(conditions, wantConds) {
+		t.Errorf("Conditions = %+v, want %+v", conditions, wantConds)
+	}
+	if want := []OrderTerm{{Column: "age", Direction: "DESC"}}; !reflect.DeepEqual(sorts, want) {
+		t.Errorf("Sorts = %v, want %v", sorts, want)
+	}
+	if limit != "20" || offset != "10" {
+		t.Errorf("Limit, Offset = %q, %q, want %q, %q", limit, offset, "20", "10")
+	}
+}
+
+func TestParsePathEmpty(t *testing.T) {
+	selects, conditions, sorts, limit, offset := ParsePath("")
+	if len(selects) != 1 || selects[0] != "*" {
+		t.Errorf("Selects = %v, want [*]", selects)
+	}
+	if conditions != nil || sorts != nil || limit != "" || offset != "" {
+		t.Errorf("Expected no conditions, sorts or slice, got %v %v %q %q", conditions, sorts, limit, offset)
+	}
+}
+
+func TestConditionString(t *testing.T) {
+	tests := []struct {
+		cond     Condition
+		expected string
+	}{
+		{Condition{Column: "status", Operator: "!=", Value: "active"}, `"status" != 'active'`},
+		{Condition{Column: "age", Operator: OpGe, Value: "18", IsNumeric: true}, `"age" >= 18`},
+		{Condition{Column: "name", Operator: "=", Value: "O'Brien"}, `"name" = 'O''Brien'`},
+		{Condition{Column: "total", Operator: OpBetween, Values: []string{"50", "500"}, IsNumeric: true}, `"total" BETWEEN 50 AND 500`},
+		{Condition{Or: []Condition{{Column: "a", Operator: OpEq, Value: "1", IsNumeric: true}, {Column: "b", Operator: OpEq, Value: "2", IsNumeric: true}}}, `("a" = 1 OR "b" = 2)`},
+	}
+	for _, tt := range tests {
+		if got := tt.cond.String(); got != tt.expected {
+			t.Errorf("String() = %q, want %q", got, tt.expected)
+		}
+	}
+}
+
+func TestBanquetConditions(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;orders;id,status!=cancelled,total>=100,region=eu|region=us?where=id>5")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	want := []Condition{
+		{Column: "status", Operator: OpNe, Value: "cancelled"},
+		{Column: "total", Operator: OpGe, Value: "100", IsNumeric: true},
+		{Or: []Condition{
+			{Column: "region", Operator: OpEq, Value: "eu"},
+			{Column: "region", Operator: OpEq, Value: "us"},
+		}},
+	}
+	if !reflect.DeepEqual(b.Conditions, want) {
+		t.Errorf("Conditions = %+v, want %+v", b.Conditions, want)
+	}
+
+	expected := `id>5 AND "status" != 'cancelled' AND "total" >= 100 AND ("region" = 'eu' OR "region" = 'us')`
+	if b.Where != expected {
+		t.Errorf("Where = %q, want %q", b.Where, expected)
+	}
+	bare := "id>5 AND status != 'cancelled' AND total >= 100 AND (region = 'eu' OR region = 'us')"
+	if got := (Renderer{}).Where(b); got != bare {
+		t.Errorf("Renderer{}.Where() = %q, want %q", got, bare)
+	}
+
+	ansi := Renderer{
+		Column: func(s string) string { return `"` + s + `"` },
+		Operator: func(op Operator) string {
+			if op == OpNe {
+				return "<>"
+			}
+			return string(op)
+		},
+	}
+	expected = `id>5 AND "status" <> 'cancelled' AND "total" >= 100 AND ("region" = 'eu' OR "region" = 'us')`
+	if got := ansi.Where(b); got != expected {
+		t.Errorf("Renderer.Where() = %q, want %q", got, expected)
+	}
+
+	clone := b.Clone()
+	clone.Conditions[2].Or[0].Value = "apac"
+	if b.Conditions[2].Or[0].Value != "eu" {
+		t.Errorf("Clone shares Conditions with the original")
+	}
+}
+
+func TestConditionGroupMissingOperator(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;notes;id,note=a|b")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if want := []Condition{{Column: "note", Operator: OpEq, Value: "a"}}; !reflect.DeepEqual(b.Conditions, want) {
+		t.Errorf("Conditions = %+v, want %+v", b.Conditions, want)
+	}
+	var verr *ValidationError
+	if len(b.Errors) != 1 || !errors.As(b.Errors[0], &verr) || verr.Field != "Conditions" {
+		t.Errorf("Errors = %v, want one Conditions *ValidationError", b.Errors)
+	}
+	if _, err := ParseBanquetWithOptions("data.sqlite;notes;id,note=a|b", ParseOptions{Strict: true}); !errors.As(err, &verr) {
+		t.Errorf("Strict parse error = %v, want *ValidationError", err)
+	}
+}
+
+func TestNumericLiterals(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"version!=1.0", `"version" != 1.0`},
+		{"id!=0", `"id" != 0`},
+		{"delta!=-0.5", `"delta" != -0.5`},
+		{"ratio!=1e3", `"ratio" != 1e3`},
+		// Leading zeros and ParseFloat-only spellings are text
+		{"zip!=00123", `"zip" != '00123'`},
+		{"id!=007", `"id" != '007'`},
+		{"code!=0x1p3", `"code" != '0x1p3'`},
+		{"name!=Inf", `"name" != 'Inf'`},
+		{"name!=nan", `"name" != 'nan'`},
+		{"zip=00100..00200", `"zip" BETWEEN '00100' AND '00200'`},
+	}
+	for _, tt := range tests {
+		_, conds, _, _, _ := ParsePath(tt.path)
+		if len(conds) != 1 {
+			t.Fatalf("ParsePath(%q) = %+v, want one condition", tt.path, conds)
+		}
+		if got := conds[0].String(); got != tt.want {
+			t.Errorf("ParsePath(%q).String() = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRendererWhereCallerFilter(t *testing.T) {
+	mysqlish := Renderer{Column: func(s string) string { return "`" + s + "`" }}
+	tests := []struct {
+		url    string
+		where  func(string) string
+		sqlite string
+		mysql  string
+	}{
+		{
+			url:    "data.sqlite;users;status!=banned?where=age>18",
+			where:  func(w string) string { return w + " AND tenant_id = 7" },
+			sqlite: `age>18 AND "status" != 'banned' AND tenant_id = 7`,
+			mysql:  "age>18 AND `status` != 'banned' AND tenant_id = 7",
+		},
+		{
+			url:    "data.sqlite;users;status!=banned",
+			where:  func(string) string { return "tenant_id = 7 OR 1 = 0" },
+			sqlite: `"status" != 'banned' AND (tenant_id = 7 OR 1 = 0)`,
+			mysql:  "`status` != 'banned' AND (tenant_id = 7 OR 1 = 0)",
+		},
+		{
+			url:    "data.sqlite;users;status!=banned",
+			where:  func(w string) string { return w },
+			sqlite: `"status" != 'banned'`,
+			mysql:  "`status` != 'banned'",
+		},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		b.Where = tt.where(b.Where)
+		if got := sqliteRenderer.Where(b); got != tt.sqlite {
+			t.Errorf("sqlite Where(%q) = %q, want %q", tt.url, got, tt.sqlite)
+		}
+		if got := mysqlish.Where(b); got != tt.mysql {
+			t.Errorf("mysql Where(%q) = %q, want %q", tt.url, got, tt.mysql)
+		}
+	}
+}
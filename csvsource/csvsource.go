@@ -0,0 +1,357 @@
+// Package csvsource answers Banquet queries against CSV files in memory.
+// The header row supplies the column names. Rows are filtered by the path conditions
+// (users.csv;age>18,city=Austin|city=Boston); raw SQL from the where param fails with ErrUnsupported.
+package csvsource
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/darianmavgo/banquet"
+)
+
+// Table is a CSV file loaded into memory.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Open reads the CSV file at path.
+func Open(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read loads CSV data from r. The first record is the header.
+func Read(r io.Reader) (*Table, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("csvsource: missing header row")
+	}
+	return &Table{Header: records[0], Rows: records[1:]}, nil
+}
+
+// ErrUnsupported is returned by Query for clauses it cannot answer, such as groupby.
+var ErrUnsupported = errors.New("csvsource: unsupported clause")
+
+// ErrNoRoot is returned by Source.Execute when Source.Root is empty.
+var ErrNoRoot = errors.New("csvsource: Source.Root is not set")
+
+// Source is a banquet.DataSource that reads the csv file at the Banquet's DataSetPath under Root.
+// Register it with banquet.RegisterSource("csv", csvsource.Source{Root: "data"}).
+type Source struct {
+	// Root is the directory DataSetPath is resolved in. DataSetPath comes from the client, so
+	// paths that leave Root, e.g. ../../etc/passwd or a symlink pointing outside, are refused.
+	Root string
+}
+
+// Execute implements banquet.DataSource.
+func (s Source) Execute(ctx context.Context, bq *banquet.Banquet) (*banquet.Rows, error) {
+	if s.Root == "" {
+		return nil, ErrNoRoot
+	}
+	f, err := os.OpenInRoot(s.Root, bq.DataSetPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := Read(f)
+	if err != nil {
+		return nil, err
+	}
+	return t.QueryRows(bq)
+}
+
+// QueryRows is Query returning banquet.Rows.
+func (t *Table) QueryRows(bq *banquet.Banquet) (*banquet.Rows, error) {
+	rows, header, err := t.Query(bq)
+	if err != nil {
+		return nil, err
+	}
+	values := make([][]any, len(rows))
+	for i, row := range rows {
+		values[i] = make([]any, len(row))
+		for j, v := range row {
+			values[i][j] = v
+		}
+	}
+	return &banquet.Rows{Columns: header, Values: values}, nil
+}
+
+// Query applies the Select, Conditions, OrderBy, Limit and Offset of bq and returns the matching rows and their header.
+// Raw SQL in Where (the where param), Distinct, GroupBy, Having and subqueries fail with
+// ErrUnsupported rather than being ignored.
+func (t *Table) Query(bq *banquet.Banquet) ([][]string, []string, error) {
+	switch {
+	case bq.Where != pathWhere(bq.Conditions):
+		return nil, nil, fmt.Errorf("%w: where %q", ErrUnsupported, bq.Where)
+	case bq.Distinct:
+		return nil, nil, fmt.Errorf("%w: distinct", ErrUnsupported)
+	case bq.GroupBy != "":
+		return nil, nil, fmt.Errorf("%w: groupby", ErrUnsupported)
+	case bq.Having != "":
+		return nil, nil, fmt.Errorf("%w: having", ErrUnsupported)
+	case bq.From != nil:
+		return nil, nil, fmt.Errorf("%w: subquery", ErrUnsupported)
+	}
+	preds := make([]func([]string) bool, len(bq.Conditions))
+	for i, cond := range bq.Conditions {
+		pred, err := t.predicate(cond)
+		if err != nil {
+			return nil, nil, err
+		}
+		preds[i] = pred
+	}
+
+	var rows [][]string
+	for _, row := range t.Rows {
+		if all(preds, row) {
+			rows = append(rows, row)
+		}
+	}
+	var err error
+
+	sorts := bq.Sorts
+	if len(sorts) == 0 && bq.OrderBy != "" {
+		sorts = []banquet.OrderTerm{{Column: bq.OrderBy, Direction: bq.SortDirection}}
+	}
+	if len(sorts) > 0 {
+		idx := make([]int, len(sorts))
+		for i, s := range sorts {
+			if idx[i], err = t.column(s.Column); err != nil {
+				return nil, nil, err
+			}
+		}
+		sort.SliceStable(rows, func(a, b int) bool {
+			for i, s := range sorts {
+				c := compare(rows[a][idx[i]], rows[b][idx[i]])
+				if c == 0 {
+					continue
+				}
+				if s.Direction == "DESC" {
+					return c > 0
+				}
+				return c < 0
+			}
+			return false
+		})
+	}
+
+	if bq.FromEnd {
+		// users.csv;users[-10:] counts from the end of the filtered rows
+		bq = bq.Clone()
+		bq.ResolveFromEnd(len(rows))
+	}
+	if bq.Offset != "" {
+		offset, err := strconv.Atoi(bq.Offset)
+		if err != nil {
+			return nil, nil, fmt.Errorf("csvsource: invalid offset %q", bq.Offset)
+		}
+		if offset > len(rows) {
+			offset = len(rows)
+		}
+		if offset > 0 {
+			rows = rows[offset:]
+		}
+	}
+	if bq.Limit != "" {
+		limit, err := strconv.Atoi(bq.Limit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("csvsource: invalid limit %q", bq.Limit)
+		}
+		if limit >= 0 && limit < len(rows) {
+			rows = rows[:limit]
+		}
+	}
+
+	// Exclusions are applied against the header, so users.csv;!password drops the column
+	selected, err := bq.ExpandSelect(t.Header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(selected) == 0 || len(selected) == 1 && selected[0] == "*" {
+		return rows, t.Header, nil
+	}
+	if slices.Contains(selected, "*") {
+		// A * next to explicit columns without exclusions, e.g. users.csv;id,*
+		selected = expandStar(selected, t.Header)
+	}
+	idx := make([]int, len(selected))
+	for i, col := range selected {
+		if idx[i], err = t.column(col); err != nil {
+			return nil, nil, err
+		}
+	}
+	projected := make([][]string, len(rows))
+	for r, row := range rows {
+		projected[r] = make([]string, len(idx))
+		for i, c := range idx {
+			projected[r][i] = row[c]
+		}
+	}
+	return projected, selected, nil
+}
+
+// expandStar replaces each * in cols with header.
+func expandStar(cols, header []string) []string {
+	var out []string
+	for _, col := range cols {
+		if col == "*" {
+			out = append(out, header...)
+		} else {
+			out = append(out, col)
+		}
+	}
+	return out
+}
+
+// column returns the index of name in the header.
+func (t *Table) column(name string) (int, error) {
+	name = unquote(name, '"')
+	for i, h := range t.Header {
+		if h == name {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("csvsource: unknown column %q", name)
+}
+
+// unquote strips a surrounding pair of q and collapses doubled q inside.
+func unquote(s string, q byte) string {
+	if len(s) >= 2 && s[0] == q && s[len(s)-1] == q {
+		return strings.ReplaceAll(s[1:len(s)-1], string(q)+string(q), string(q))
+	}
+	return s
+}
+
+// pathWhere renders conds as parsing does for Banquet.Where. A Where that differs carries SQL
+// the table can't evaluate, from the where param or added by the caller.
+func pathWhere(conds []banquet.Condition) string {
+	parts := make([]string, len(conds))
+	for i, cond := range conds {
+		parts[i] = cond.String()
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// comparisons maps the simple operators to the compare results they accept.
+var comparisons = map[banquet.Operator]func(int) bool{
+	banquet.OpEq: func(c int) bool { return c == 0 },
+	banquet.OpNe: func(c int) bool { return c != 0 },
+	banquet.OpLt: func(c int) bool { return c < 0 },
+	banquet.OpLe: func(c int) bool { return c <= 0 },
+	banquet.OpGt: func(c int) bool { return c > 0 },
+	banquet.OpGe: func(c int) bool { return c >= 0 },
+}
+
+// predicate compiles cond into a test on a row, resolving its columns against the header.
+// Shapes it can't evaluate fail with ErrUnsupported.
+func (t *Table) predicate(cond banquet.Condition) (func([]string) bool, error) {
+	if len(cond.Or) > 0 {
+		alts := make([]func([]string) bool, len(cond.Or))
+		for i, alt := range cond.Or {
+			pred, err := t.predicate(alt)
+			if err != nil {
+				return nil, err
+			}
+			alts[i] = pred
+		}
+		return func(row []string) bool {
+			return slices.ContainsFunc(alts, func(alt func([]string) bool) bool { return alt(row) })
+		}, nil
+	}
+	if cond.Operator == banquet.OpIn && len(cond.Columns) > 0 {
+		idx := make([]int, len(cond.Columns))
+		for i, name := range cond.Columns {
+			var err error
+			if idx[i], err = t.column(name); err != nil {
+				return nil, err
+			}
+		}
+		return func(row []string) bool {
+			return slices.ContainsFunc(cond.Tuples, func(tuple []string) bool {
+				if len(tuple) != len(idx) {
+					return false
+				}
+				for i, c := range idx {
+					if compare(row[c], tuple[i]) != 0 {
+						return false
+					}
+				}
+				return true
+			})
+		}, nil
+	}
+
+	col, err := t.column(cond.Column)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case cond.Operator == banquet.OpBetween && len(cond.Values) == 2:
+		lo, hi := cond.Values[0], cond.Values[1]
+		return func(row []string) bool {
+			return compare(row[col], lo) >= 0 && compare(row[col], hi) <= 0
+		}, nil
+	case cond.Operator == banquet.OpLike:
+		// Contains, case-insensitively like SQLite's LIKE
+		needle := strings.ToLower(cond.Value)
+		return func(row []string) bool {
+			return strings.Contains(strings.ToLower(row[col]), needle)
+		}, nil
+	}
+	accept, ok := comparisons[cond.Operator]
+	if !ok {
+		return nil, fmt.Errorf("%w: condition %s", ErrUnsupported, cond)
+	}
+	if cond.IsColumn {
+		// a>@b compares two columns of the same row
+		other, err := t.column(cond.Value)
+		if err != nil {
+			return nil, err
+		}
+		return func(row []string) bool { return accept(compare(row[col], row[other])) }, nil
+	}
+	return func(row []string) bool { return accept(compare(row[col], cond.Value)) }, nil
+}
+
+// all reports whether row passes every predicate.
+func all(preds []func([]string) bool, row []string) bool {
+	for _, pred := range preds {
+		if !pred(row) {
+			return false
+		}
+	}
+	return true
+}
+
+// compare orders numerically when both values are numbers and lexically otherwise.
+func compare(a, b string) int {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,206 @@
+package csvsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darianmavgo/banquet"
+)
+
+const sample = `id,name,city,age
+1,Ann,"Portland, OR",34
+2,Bob,Austin,17
+3,Cid,"Portland, OR",52
+4,Dee,Boston,41
+5,Eve,Austin,29
+`
+
+func query(t *testing.T, rawurl string) ([][]string, []string, error) {
+	t.Helper()
+	table, err := Read(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	bq, err := banquet.ParseBanquet(rawurl)
+	if err != nil {
+		t.Fatalf("ParseBanquet(%q) failed: %v", rawurl, err)
+	}
+	return table.Query(bq)
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		url    string
+		header []string
+		rows   [][]string
+	}{
+		{
+			url:    "people.csv",
+			header: []string{"id", "name", "city", "age"},
+			rows: [][]string{
+				{"1", "Ann", "Portland, OR", "34"},
+				{"2", "Bob", "Austin", "17"},
+				{"3", "Cid", "Portland, OR", "52"},
+				{"4", "Dee", "Boston", "41"},
+				{"5", "Eve", "Austin", "29"},
+			},
+		},
+		{
+			url:    "people.csv;name,city",
+			header: []string{"name", "city"},
+			rows: [][]string{
+				{"Ann", "Portland, OR"},
+				{"Bob", "Austin"},
+				{"Cid", "Portland, OR"},
+				{"Dee", "Boston"},
+				{"Eve", "Austin"},
+			},
+		},
+		{
+			url:    "people.csv;name,city!=Austin",
+			header: []string{"name"},
+			rows:   [][]string{{"Ann"}, {"Cid"}, {"Dee"}},
+		},
+		{
+			url:    "people.csv;name,city=Austin",
+			header: []string{"name"},
+			rows:   [][]string{{"Bob"}, {"Eve"}},
+		},
+		{
+			url:    "people.csv;name,city=Boston|age<20",
+			header: []string{"name"},
+			rows:   [][]string{{"Bob"}, {"Dee"}},
+		},
+		{
+			// @city is the city column of the same row, not the text "city"
+			url:    "people.csv;name,name<@city",
+			header: []string{"name"},
+			rows:   [][]string{{"Ann"}, {"Cid"}},
+		},
+		{
+			url:    "people.csv;name,age=20..40,city~PORT",
+			header: []string{"name"},
+			rows:   [][]string{{"Ann"}},
+		},
+		{
+			url:    "people.csv;name,(city,age)=[(Austin,17),(Boston,41)]",
+			header: []string{"name"},
+			rows:   [][]string{{"Bob"}, {"Dee"}},
+		},
+		{
+			url:    "people.csv;name,-age,age>18",
+			header: []string{"name"},
+			rows:   [][]string{{"Cid"}, {"Dee"}, {"Ann"}, {"Eve"}},
+		},
+		{
+			url:    "people.csv;!city,!age",
+			header: []string{"id", "name"},
+			rows:   [][]string{{"1", "Ann"}, {"2", "Bob"}, {"3", "Cid"}, {"4", "Dee"}, {"5", "Eve"}},
+		},
+		{
+			url:    "people.csv;id,*,!city,!age",
+			header: []string{"id", "name"},
+			rows:   [][]string{{"1", "Ann"}, {"2", "Bob"}, {"3", "Cid"}, {"4", "Dee"}, {"5", "Eve"}},
+		},
+		{
+			url:    "people.csv;name,+age[1:3]",
+			header: []string{"name"},
+			rows:   [][]string{{"Eve"}, {"Ann"}},
+		},
+		{
+			url:    "people.csv;name,+age[-2:]",
+			header: []string{"name"},
+			rows:   [][]string{{"Dee"}, {"Cid"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			rows, header, err := query(t, tt.url)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if strings.Join(header, "|") != strings.Join(tt.header, "|") {
+				t.Errorf("header = %v, want %v", header, tt.header)
+			}
+			if len(rows) != len(tt.rows) {
+				t.Fatalf("rows = %v, want %v", rows, tt.rows)
+			}
+			for i := range rows {
+				if strings.Join(rows[i], "|") != strings.Join(tt.rows[i], "|") {
+					t.Errorf("rows[%d] = %v, want %v", i, rows[i], tt.rows[i])
+				}
+			}
+		})
+	}
+}
+
+func TestQueryMissingColumn(t *testing.T) {
+	for _, u := range []string{"people.csv;name,email", "people.csv;name,email=x", "people.csv;name,age>@email", "people.csv;name,+email"} {
+		if _, _, err := query(t, u); err == nil {
+			t.Errorf("Query(%q) expected unknown column error", u)
+		}
+	}
+}
+
+func TestQueryUnsupported(t *testing.T) {
+	for _, u := range []string{
+		"people.csv;name?where=city='Austin'",
+		"people.csv;name?where=city=%27Boston%27%20OR%20age<20",
+		"people.csv;city?distinct=true",
+		"people.csv;city?groupby=city",
+		"people.csv;city?groupby=city&having=count(*)>1",
+	} {
+		rows, _, err := query(t, u)
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Query(%q) error = %v, want ErrUnsupported", u, err)
+		}
+		if rows != nil {
+			t.Errorf("Query(%q) rows = %v, want none", u, rows)
+		}
+	}
+
+	// A filter added to Where by hand is raw SQL too
+	bq, err := banquet.ParseBanquet("people.csv;name,city=Austin")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	bq.Where += " AND age > 20"
+	table, _ := Read(strings.NewReader(sample))
+	if _, _, err := table.Query(bq); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Query with an added Where error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestSourceRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "people.csv"), []byte(sample), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	execute := func(src Source, rawurl string) (*banquet.Rows, error) {
+		bq, err := banquet.ParseBanquet(rawurl)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", rawurl, err)
+		}
+		return src.Execute(context.Background(), bq)
+	}
+
+	rows, err := execute(Source{Root: root}, "people.csv;name,city=Austin")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := fmt.Sprint(rows.Values); got != "[[Bob] [Eve]]" {
+		t.Errorf("Execute = %s, want [[Bob] [Eve]]", got)
+	}
+	// DataSetPath is client controlled, so it can't climb out of Root
+	if _, err := execute(Source{Root: filepath.Join(root, "sub")}, "../people.csv"); err == nil {
+		t.Error("Execute(../people.csv) expected an error for a path outside Root")
+	}
+	if _, err := execute(Source{}, "people.csv"); !errors.Is(err, ErrNoRoot) {
+		t.Errorf("Execute without Root error = %v, want ErrNoRoot", err)
+	}
+}
@@ -0,0 +1,58 @@
+package banquet
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNoColumns is returned by ExpandSelect when exclusions apply to * and no column list
+// was provided. SQL has no portable SELECT * EXCEPT, so the columns must come from a schema.
+var ErrNoColumns = errors.New("banquet: column list required to expand exclusions")
+
+// ExpandSelect returns the select list of b with b.Exclude applied. A * (alone, as in
+// users;*,!password, or next to explicit columns, as in users;id,*) expands in place to columns,
+// typically the table schema, minus the exclusions and the explicit columns; explicit columns keep
+// their position and are filtered too, so users;name,*,!password selects name once. Without exclusions the list is returned as is, and without a * columns is not
+// needed. Names match case-insensitively.
+func (b *Banquet) ExpandSelect(columns []string) ([]string, error) {
+	if len(b.Exclude) == 0 {
+		return b.Select, nil
+	}
+	source := b.Select
+	if len(source) == 0 {
+		source = []string{"*"}
+	}
+	var out []string
+	for _, col := range source {
+		if col == "*" {
+			if len(columns) == 0 {
+				return nil, ErrNoColumns
+			}
+			for _, c := range columns {
+				if !b.excludes(c) && !containsFold(source, c) {
+					out = append(out, c)
+				}
+			}
+			continue
+		}
+		if !b.excludes(col) {
+			out = append(out, col)
+		}
+	}
+	return out, nil
+}
+
+// excludes reports whether col is listed in b.Exclude.
+func (b *Banquet) excludes(col string) bool {
+	return containsFold(b.Exclude, col)
+}
+
+// containsFold reports whether list holds col, ignoring case.
+func containsFold(list []string, col string) bool {
+	for _, c := range list {
+		if strings.EqualFold(c, col) {
+			return true
+		}
+	}
+	return false
+}
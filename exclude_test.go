@@ -0,0 +1,92 @@
+package banquet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseExclusions(t *testing.T) {
+	tests := []struct {
+		url     string
+		selects []string
+		exclude []string
+	}{
+		{"data.sqlite;users;!password,!ssn", []string{"*"}, []string{"password", "ssn"}},
+		{"data.sqlite;users;id,name,!ssn,-age", []string{"id", "name"}, []string{"ssn"}},
+		{"data.sqlite;users;!password[0:10]", []string{"*"}, []string{"password"}},
+		// !^ stays a literal column name
+		{"data.sqlite;users;^column1,!^column2", []string{"^column1", "!^column2"}, nil},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if !reflect.DeepEqual(b.Select, tt.selects) {
+			t.Errorf("ParseBanquet(%q) Select = %v, want %v", tt.url, b.Select, tt.selects)
+		}
+		if !reflect.DeepEqual(b.Exclude, tt.exclude) {
+			t.Errorf("ParseBanquet(%q) Exclude = %v, want %v", tt.url, b.Exclude, tt.exclude)
+		}
+	}
+}
+
+func TestExpandSelect(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;users;!password,!SSN")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if _, err := b.ExpandSelect(nil); !errors.Is(err, ErrNoColumns) {
+		t.Errorf("Expected ErrNoColumns without a column list, got %v", err)
+	}
+	got, err := b.ExpandSelect([]string{"id", "name", "password", "ssn"})
+	if err != nil {
+		t.Fatalf("ExpandSelect error: %v", err)
+	}
+	if want := []string{"id", "name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandSelect() = %v, want %v", got, want)
+	}
+
+	// Explicit columns don't need a schema
+	b, _ = ParseBanquet("data.sqlite;users;id,ssn,!ssn")
+	got, err = b.ExpandSelect(nil)
+	if err != nil {
+		t.Fatalf("ExpandSelect error: %v", err)
+	}
+	if want := []string{"id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandSelect() = %v, want %v", got, want)
+	}
+}
+
+func TestWildcardWithExclusions(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;users;*,!password")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if !b.SelectAll || !reflect.DeepEqual(b.Select, []string{"*"}) || !reflect.DeepEqual(b.Exclude, []string{"password"}) {
+		t.Errorf("got SelectAll %v Select %v Exclude %v", b.SelectAll, b.Select, b.Exclude)
+	}
+	if _, err := b.ExpandSelect(nil); !errors.Is(err, ErrNoColumns) {
+		t.Errorf("Expected ErrNoColumns without a schema, got %v", err)
+	}
+	schema := []string{"id", "name", "password"}
+	if got, _ := b.ExpandSelect(schema); !reflect.DeepEqual(got, []string{"id", "name"}) {
+		t.Errorf("ExpandSelect() = %v", got)
+	}
+
+	// Explicit columns next to * keep their position; * expands in place to the rest
+	b, err = ParseBanquet("data.sqlite;users;name,*,!password")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if !b.SelectAll || !reflect.DeepEqual(b.Select, []string{"name", "*"}) {
+		t.Errorf("got SelectAll %v Select %v", b.SelectAll, b.Select)
+	}
+	if got, _ := b.ExpandSelect(schema); !reflect.DeepEqual(got, []string{"name", "id"}) {
+		t.Errorf("ExpandSelect() = %v", got)
+	}
+	if _, err := b.ExpandSelect(nil); !errors.Is(err, ErrNoColumns) {
+		t.Errorf("Expected ErrNoColumns without a schema, got %v", err)
+	}
+}
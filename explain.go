@@ -0,0 +1,101 @@
+package banquet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explain returns a line per populated clause of b, e.g.
+//
+//	dataset: data.sqlite
+//	table: users
+//	select: id
+//	condition: status != 'active'
+//	sort: age DESC
+//	limit: 10
+//
+// Unlike FmtPrintln, which prints the raw URL fields, Explain shows what ParseBanquet made of them:
+// every selected column, each condition with its operator, sorts, paging, grouping and warnings.
+func Explain(b *Banquet) string {
+	var lines []string
+	add := func(label, value string) {
+		if value != "" {
+			lines = append(lines, label+": "+value)
+		}
+	}
+
+	add("dataset", b.DataSetPath)
+	add("schema", b.Schema)
+	add("table", b.Table)
+	for _, col := range b.Select {
+		add("select", col)
+	}
+	for _, col := range b.Exclude {
+		add("exclude", col)
+	}
+	if b.Distinct {
+		add("distinct", "true")
+	}
+	for _, c := range b.Conditions {
+		add("condition", explainCondition(c))
+	}
+	if b.URL != nil {
+		for _, where := range rawQueryValues(b.RawQuery, "where") {
+			add("where", where)
+		}
+	}
+	for _, sort := range b.Sorts {
+		term := strings.TrimSpace(sort.Column + " " + sort.Direction)
+		if sort.Nulls != "" {
+			term += " NULLS " + sort.Nulls
+		}
+		add("sort", term)
+	}
+	add("limit", b.Limit)
+	add("offset", b.Offset)
+	if b.FromEnd {
+		add("slice", "["+b.sliceStart+":"+b.sliceEnd+"] from the end")
+	}
+	add("groupby", b.GroupBy)
+	add("having", b.Having)
+	add("rawsql", b.RawSQL)
+	for _, w := range b.Warnings {
+		add("warning", w)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// explainCondition spells c as column operator value, with string values in single quotes,
+// @col for column references and OR between alternatives.
+func explainCondition(c Condition) string {
+	if len(c.Or) > 0 {
+		alts := make([]string, len(c.Or))
+		for i, alt := range c.Or {
+			alts[i] = explainCondition(alt)
+		}
+		return strings.Join(alts, " OR ")
+	}
+	value := func(v string) string {
+		if c.IsNumeric {
+			return v
+		}
+		return fmt.Sprintf("'%s'", v)
+	}
+	switch {
+	case c.Operator == OpBetween && len(c.Values) == 2:
+		return c.Column + " BETWEEN " + value(c.Values[0]) + " AND " + value(c.Values[1])
+	case c.Operator == OpIn && len(c.Columns) > 0:
+		tuples := make([]string, len(c.Tuples))
+		for i, tuple := range c.Tuples {
+			vals := make([]string, len(tuple))
+			for j, val := range tuple {
+				vals[j] = literal(val, isNumeric(val))
+			}
+			tuples[i] = "(" + strings.Join(vals, ", ") + ")"
+		}
+		return "(" + strings.Join(c.Columns, ", ") + ") IN (" + strings.Join(tuples, ", ") + ")"
+	case c.IsColumn:
+		return c.Column + " " + string(c.Operator) + " @" + c.Value
+	}
+	return c.Column + " " + string(c.Operator) + " " + value(c.Value)
+}
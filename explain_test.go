@@ -0,0 +1,39 @@
+package banquet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;main.users;id,name,!password,-age,status!=active,score=1..5,a=1|b=@c[10:20]?where=age>18&groupby=country&having=count(*)>5&distinct=true")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	got := Explain(b)
+	for _, want := range []string{
+		"dataset: ./data.sqlite", // the colon in [10:20] is protected with ./
+		"schema: main",
+		"table: users",
+		"select: id",
+		"select: name",
+		"exclude: password",
+		"distinct: true",
+		"condition: status != 'active'",
+		"condition: score BETWEEN 1 AND 5",
+		"condition: a = 1 OR b = @c",
+		"where: age>18",
+		"sort: age DESC",
+		"limit: 10",
+		"offset: 10",
+		"groupby: country",
+		"having: count(*) > 5",
+	} {
+		if !strings.Contains(got+"\n", want+"\n") {
+			t.Errorf("Explain() is missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "warning:") {
+		t.Errorf("Explain() reported warnings:\n%s", got)
+	}
+}
@@ -0,0 +1,179 @@
+package banquet
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ScalarFunctions lists the functions that may wrap a column in the select list or a sort,
+// e.g. users;upper(name),+date(created_at). Keys are lower case; add entries to allow more.
+var ScalarFunctions = map[string]bool{
+	"abs":      true,
+	"coalesce": true,
+	"date":     true,
+	"length":   true,
+	"lower":    true,
+	"round":    true,
+	"substr":   true,
+	"trim":     true,
+	"upper":    true,
+}
+
+// AggregateFunctions lists the functions ValidateGrouping accepts around a column that isn't
+// in GroupBy, e.g. count(id) or sum(total). Keys are lower case; add entries to allow more.
+var AggregateFunctions = map[string]bool{
+	"array_agg":    true,
+	"avg":          true,
+	"count":        true,
+	"group_concat": true,
+	"max":          true,
+	"min":          true,
+	"string_agg":   true,
+	"sum":          true,
+	"total":        true,
+}
+
+// functionCall matches a function name and its opening parenthesis.
+var functionCall = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\s*\(`)
+
+// isAggregate reports whether col calls one of AggregateFunctions, at any depth, as in
+// count(*) or round(avg(price),2).
+func isAggregate(col string) bool {
+	for _, m := range functionCall.FindAllStringSubmatch(col, -1) {
+		if AggregateFunctions[strings.ToLower(m[1])] {
+			return true
+		}
+	}
+	return false
+}
+
+// literalArg matches the function arguments rendered as is: numbers and single-quoted strings
+// without embedded quotes.
+var literalArg = regexp.MustCompile(`^(-?\d+(\.\d+)?|'[^']*')$`)
+
+// QuoteColumn quotes col with quote. A call to one of ScalarFunctions keeps the function name
+// bare and quotes only its column arguments, so upper(name) becomes upper("name") and
+// substr(name,1,3) becomes substr("name", 1, 3). Simple arithmetic quotes its column operands,
+// so total*1.1 becomes "total" * 1.1 (see arithmetic). Anything else is quoted whole.
+func QuoteColumn(col string, quote func(string) string) string {
+	if operands, ops, ok := arithmetic(col); ok {
+		var out strings.Builder
+		for i, operand := range operands {
+			if i > 0 {
+				out.WriteString(" " + string(ops[i-1]) + " ")
+			}
+			if numberLiteral.MatchString(operand) {
+				out.WriteString(operand)
+			} else {
+				out.WriteString(QuoteColumn(operand, quote))
+			}
+		}
+		return out.String()
+	}
+	name, args, ok := scalarCall(col)
+	if !ok {
+		return quote(col)
+	}
+	for i, arg := range args {
+		if !literalArg.MatchString(arg) {
+			args[i] = quote(arg)
+		}
+	}
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// scalarCall splits col into a ScalarFunctions name and its trimmed arguments. Every argument
+// must be a bare column name or a literal.
+func scalarCall(col string) (string, []string, bool) {
+	open := strings.IndexByte(col, '(')
+	if open < 1 || !strings.HasSuffix(col, ")") || !ScalarFunctions[strings.ToLower(col[:open])] {
+		return "", nil, false
+	}
+	inner := col[open+1 : len(col)-1]
+	if strings.ContainsAny(inner, "()") {
+		return "", nil, false
+	}
+	args := strings.Split(inner, ",")
+	for i, arg := range args {
+		args[i] = strings.TrimSpace(arg)
+		if !isBareIdentifier(args[i]) && !literalArg.MatchString(args[i]) {
+			return "", nil, false
+		}
+	}
+	return col[:open], args, true
+}
+
+// arithmetic splits col into operands and the + - * / operators between them, e.g. total*1.1
+// or round(price,2)+tax. Each operand is a bare column name, a number or a ScalarFunctions call.
+// A leading + or - is a sort prefix rather than arithmetic, and a - directly after another
+// operator signs a number (total*-1). A - with a letter, digit or _ right on both sides joins
+// one name, so first-name, address-2 and utf-8 stay names; subtraction needs spaces
+// (total - 5) or an operand that isn't a name, as in round(total,2)-1. Operators inside a
+// call's parentheses belong to the call.
+func arithmetic(col string) (operands []string, ops []byte, ok bool) {
+	depth, start := 0, 0
+	for i := 0; i < len(col); i++ {
+		switch c := col[i]; {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '-' && i > 0 && i+1 < len(col) && isIdentChar(col[i-1]) && isIdentChar(col[i+1]):
+			// Part of a hyphenated name
+		case depth == 0 && strings.IndexByte("+-*/", c) != -1:
+			if strings.TrimSpace(col[start:i]) == "" {
+				// A sign belongs to the number that follows, anything else is malformed
+				if c != '-' || len(ops) == 0 || i+1 >= len(col) || col[i+1] < '0' || col[i+1] > '9' {
+					return nil, nil, false
+				}
+				continue
+			}
+			operands = append(operands, strings.TrimSpace(col[start:i]))
+			ops = append(ops, c)
+			start = i + 1
+		}
+	}
+	operands = append(operands, strings.TrimSpace(col[start:]))
+	if len(ops) == 0 || depth != 0 {
+		return nil, nil, false
+	}
+	for _, operand := range operands {
+		switch {
+		case numberLiteral.MatchString(operand) && operand != "":
+		case isBareIdentifier(strings.ReplaceAll(operand, "-", "_")):
+		default:
+			if _, _, ok := scalarCall(operand); !ok {
+				return nil, nil, false
+			}
+		}
+	}
+	return operands, ops, true
+}
+
+// splitColumns splits s on commas outside parentheses and brackets, keeping a multi-argument
+// call such as substr(name,1,3) or a tuple IN list in one token. An escaped \, doesn't split.
+// Unbalanced parentheses fall back to a plain split.
+func splitColumns(s string) []string {
+	var tokens []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+			if depth < 0 {
+				return strings.Split(s, ",")
+			}
+		case ',':
+			if depth == 0 && (i == 0 || s[i-1] != '\\') {
+				tokens = append(tokens, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return strings.Split(s, ",")
+	}
+	return append(tokens, s[start:])
+}
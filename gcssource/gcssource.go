@@ -0,0 +1,157 @@
+// Package gcssource fetches the object addressed by a gs:// Banquet from Google Cloud Storage.
+// It talks to the GCS JSON API over net/http so the module stays dependency free; to use
+// Application Default Credentials or the official client, wrap it in an ObjectStore.
+package gcssource
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/darianmavgo/banquet"
+	"github.com/darianmavgo/banquet/csvsource"
+	"github.com/darianmavgo/banquet/xlsxsource"
+)
+
+// ErrUnsupported is returned by OpenTable and Source.Execute for objects that are neither
+// .csv nor .xlsx.
+var ErrUnsupported = errors.New("gcssource: unsupported object type")
+
+// ObjectStore reads an object from a bucket. The official client satisfies it with
+// client.Bucket(bucket).Object(object).NewReader(ctx).
+type ObjectStore interface {
+	NewReader(ctx context.Context, bucket, object string) (io.ReadCloser, error)
+}
+
+// DefaultEndpoint is the public GCS API endpoint used by HTTPStore.
+const DefaultEndpoint = "https://storage.googleapis.com"
+
+// HTTPStore downloads objects with the GCS JSON API.
+type HTTPStore struct {
+	Client   *http.Client // nil uses http.DefaultClient.
+	Endpoint string       // Empty uses DefaultEndpoint.
+	Token    string       // OAuth2 access token sent as a bearer token; empty for public objects.
+}
+
+// NewReader implements ObjectStore.
+func (s *HTTPStore) NewReader(ctx context.Context, bucket, object string) (io.ReadCloser, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	target := endpoint + "/storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(object) + "?alt=media"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("gcssource: gs://%s/%s: %s", bucket, object, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// Open fetches the object bq addresses through an HTTPStore, authenticating with the token
+// carried in the URL userinfo (gs://token@bucket/file.csv) when present.
+func Open(ctx context.Context, bq *banquet.Banquet) (io.ReadCloser, error) {
+	return OpenWith(ctx, urlStore(bq), bq)
+}
+
+// urlStore is the HTTPStore Open reads through, using the token in the URL userinfo.
+func urlStore(bq *banquet.Banquet) *HTTPStore {
+	token := bq.Auth.Token
+	if token == "" {
+		token = bq.Auth.Password
+	}
+	return &HTTPStore{Token: token}
+}
+
+// OpenWith is like Open but reads through store.
+func OpenWith(ctx context.Context, store ObjectStore, bq *banquet.Banquet) (io.ReadCloser, error) {
+	bucket, object, err := Location(bq)
+	if err != nil {
+		return nil, err
+	}
+	return store.NewReader(ctx, bucket, object)
+}
+
+// OpenTable fetches an object through store and loads it by its extension: .csv with
+// csvsource, and .xlsx with xlsxsource, reading the worksheet named by the table tier.
+// Other objects fail with ErrUnsupported before they are fetched.
+func OpenTable(ctx context.Context, store ObjectStore, bq *banquet.Banquet) (*csvsource.Table, error) {
+	_, object, err := Location(bq)
+	if err != nil {
+		return nil, err
+	}
+	ext := strings.ToLower(path.Ext(object))
+	if ext != ".csv" && ext != ".xlsx" {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, object)
+	}
+	rc, err := OpenWith(ctx, store, bq)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	if ext == ".csv" {
+		return csvsource.Read(rc)
+	}
+	// A workbook is a zip archive, which needs random access
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	wb, err := xlsxsource.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return xlsxsource.Load(wb, bq.QualifiedTable())
+}
+
+// Source is a banquet.DataSource for csv and xlsx objects in GCS.
+// Register it with banquet.RegisterSource("gs", gcssource.Source{Store: store}).
+type Source struct {
+	Store ObjectStore // nil reads through an HTTPStore using the URL's token, like Open.
+}
+
+// Execute implements banquet.DataSource.
+func (s Source) Execute(ctx context.Context, bq *banquet.Banquet) (*banquet.Rows, error) {
+	var store ObjectStore = urlStore(bq)
+	if s.Store != nil {
+		store = s.Store
+	}
+	table, err := OpenTable(ctx, store, bq)
+	if err != nil {
+		return nil, err
+	}
+	return table.QueryRows(bq)
+}
+
+// Location returns the bucket (Host) and object name (DataSetPath) of a gs:// Banquet.
+func Location(bq *banquet.Banquet) (bucket, object string, err error) {
+	if bq.URL == nil || bq.Scheme != "gs" {
+		return "", "", fmt.Errorf("gcssource: not a gs:// URL")
+	}
+	bucket = bq.Hostname()
+	object = strings.TrimPrefix(bq.DataSetPath, "/")
+	if bucket == "" || object == "" {
+		return "", "", fmt.Errorf("gcssource: gs:// URL needs a bucket and an object")
+	}
+	return bucket, object, nil
+}
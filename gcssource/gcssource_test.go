@@ -0,0 +1,159 @@
+package gcssource
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/darianmavgo/banquet"
+)
+
+// fakeStore serves objects from memory keyed by bucket/object.
+type fakeStore map[string]string
+
+func (f fakeStore) NewReader(ctx context.Context, bucket, object string) (io.ReadCloser, error) {
+	data, ok := f[bucket+"/"+object]
+	if !ok {
+		return nil, fmt.Errorf("object %s/%s not found", bucket, object)
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+func TestOpenTable(t *testing.T) {
+	store := fakeStore{"bucket/exports/users.csv": "id,name\n1,Ann\n2,Bob\n"}
+	bq, err := banquet.ParseBanquet("gs://bucket/exports/users.csv;id,name,id>1")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	table, err := OpenTable(context.Background(), store, bq)
+	if err != nil {
+		t.Fatalf("OpenTable error: %v", err)
+	}
+	rows, _, err := table.Query(bq)
+	if err != nil {
+		t.Fatalf("Query error: %v", err)
+	}
+	if len(rows) != 1 || rows[0][1] != "Bob" {
+		t.Errorf("Query rows = %v, want [[2 Bob]]", rows)
+	}
+}
+
+// workbook builds an .xlsx archive with one worksheet, People, using inline strings.
+func workbook(t *testing.T) string {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="People" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>name</t></is></c><c r="B1" t="inlineStr"><is><t>age</t></is></c></row>
+<row r="2"><c r="A2" t="inlineStr"><is><t>Ann</t></is></c><c r="B2"><v>34</v></c></row>
+<row r="3"><c r="A3" t="inlineStr"><is><t>Bob</t></is></c><c r="B3"><v>17</v></c></row></sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestSourceXLSX(t *testing.T) {
+	store := fakeStore{
+		"bucket/exports/people.xlsx":    workbook(t),
+		"bucket/exports/people.parquet": "PAR1",
+	}
+	bq, err := banquet.ParseBanquet("gs://bucket/exports/people.xlsx;People;name,age<30")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	src := Source{Store: store}
+	rows, err := src.Execute(context.Background(), bq)
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if got := fmt.Sprint(rows.Columns, rows.Values); got != "[name] [[Bob]]" {
+		t.Errorf("Execute = %s, want [name] [[Bob]]", got)
+	}
+
+	bq, err = banquet.ParseBanquet("gs://bucket/exports/people.parquet")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if _, err := src.Execute(context.Background(), bq); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Execute(.parquet) error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	for _, raw := range []string{"data.csv", "https://host/data.csv", "gs://bucket"} {
+		bq, err := banquet.ParseBanquet(raw)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", raw, err)
+		}
+		if _, _, err := Location(bq); err == nil {
+			t.Errorf("Location(%q) expected error", raw)
+		}
+	}
+}
+
+func TestHTTPStore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/storage/v1/b/bucket/o/exports%2Fusers.csv" || r.URL.Query().Get("alt") != "media" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, "id\n1\n")
+	}))
+	defer server.Close()
+
+	bq, err := banquet.ParseBanquet("gs://secret@bucket/exports/users.csv")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	rc, err := OpenWith(context.Background(), &HTTPStore{Endpoint: server.URL, Token: bq.Auth.Token}, bq)
+	if err != nil {
+		t.Fatalf("OpenWith error: %v", err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	if string(data) != "id\n1\n" {
+		t.Errorf("object = %q, want %q", data, "id\n1\n")
+	}
+
+	if _, err := OpenWith(context.Background(), &HTTPStore{Endpoint: server.URL}, bq); err == nil {
+		t.Errorf("Expected error without a token")
+	}
+}
+
+func TestSourceExecute(t *testing.T) {
+	banquet.RegisterSource("gs", Source{Store: fakeStore{"bucket/users.csv": "id,name\n1,Ann\n2,Bob\n"}})
+	rows, err := banquet.Execute(context.Background(), "gs://bucket/users.csv;name[1]")
+	if err != nil {
+		t.Fatalf("Execute error: %v", err)
+	}
+	if len(rows.Columns) != 1 || rows.Columns[0] != "name" || len(rows.Values) != 1 || rows.Values[0][0] != "Ann" {
+		t.Errorf("Execute = %+v, want name column with Ann", rows)
+	}
+}
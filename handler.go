@@ -0,0 +1,106 @@
+package banquet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Handler returns an http.Handler that parses each request with FromRequest, executes it
+// against the DataSource resolved from sources and writes the rows as CSV or JSON.
+// The format comes from the format query param (csv or json), else Banquet.OutputFormat,
+// else the Accept header, and defaults to JSON. A nil sources uses the package registry
+// (see RegisterSource).
+//
+// Parse and validation errors answer 400, an unknown format 406, a dataset with no
+// registered source 404 and execution errors 500. A 500 body is the bare status text so
+// file paths and driver messages don't reach the client; the error itself is logged.
+func Handler(sources Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registry := sources
+		if registry == nil {
+			registry = defaultRegistry()
+		}
+		format, ok := responseFormat(r)
+		if !ok {
+			http.Error(w, fmt.Sprintf("unsupported format %q", r.URL.Query().Get("format")), http.StatusNotAcceptable)
+			return
+		}
+		bq, err := FromRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// A suffix such as data.sqlite;users.csv stands in for the format param
+		if r.URL.Query().Get("format") == "" && bq.OutputFormat != "" {
+			format = bq.OutputFormat
+		}
+		src, err := registry.Resolve(bq)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		rows, err := src.Execute(r.Context(), bq)
+		if err != nil {
+			var verr *ValidationError
+			if errors.As(err, &verr) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Printf("[BANQUET] executing %s: %v", bq.DataSetPath, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			writeCSV(w, rows)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rows)
+	})
+}
+
+// defaultRegistry returns the package registry. It is read per request so sources
+// registered after Handler was called are still found.
+func defaultRegistry() Registry {
+	return sources
+}
+
+// responseFormat picks csv or json from the format param or the Accept header.
+// It reports false for a format param it doesn't know.
+func responseFormat(r *http.Request) (string, bool) {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		return format, format == "csv" || format == "json"
+	}
+	if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		return "csv", true
+	}
+	return "json", true
+}
+
+// writeCSV writes a header row of column names followed by the values, with nil as an empty field.
+func writeCSV(w http.ResponseWriter, rows *Rows) {
+	cw := csv.NewWriter(w)
+	cw.Write(rows.Columns)
+	record := make([]string, len(rows.Columns))
+	for _, row := range rows.Values {
+		for i := range record {
+			record[i] = ""
+			if i < len(row) && row[i] != nil {
+				if b, ok := row[i].([]byte); ok {
+					record[i] = string(b)
+				} else {
+					record[i] = fmt.Sprint(row[i])
+				}
+			}
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+}
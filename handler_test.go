@@ -0,0 +1,93 @@
+package banquet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// errSource fails every query with err.
+type errSource struct{ err error }
+
+func (e errSource) Execute(ctx context.Context, bq *Banquet) (*Rows, error) {
+	return nil, e.err
+}
+
+func TestHandlerCSV(t *testing.T) {
+	h := Handler(Registry{"csv": fakeSource("csv")})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/data/users.csv;id?format=csv", nil),
+		httptest.NewRequest("GET", "/data/users.csv;;id.csv", nil),
+		func() *http.Request {
+			r := httptest.NewRequest("GET", "/data/users.csv;id", nil)
+			r.Header.Set("Accept", "text/csv")
+			return r
+		}(),
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", req.URL, rec.Code, rec.Body)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+			t.Errorf("%s: Content-Type = %q", req.URL, ct)
+		}
+		if got, want := rec.Body.String(), "source,table\ncsv,\n"; got != want {
+			t.Errorf("%s: body = %q, want %q", req.URL, got, want)
+		}
+	}
+}
+
+func TestHandlerJSON(t *testing.T) {
+	h := Handler(Registry{"sqlite": fakeSource("sqlite")})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/data.sqlite;users;id,name", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var rows Rows
+	if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body, err)
+	}
+	if len(rows.Values) != 1 || rows.Values[0][0] != "sqlite" || rows.Values[0][1] != "users" {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	registry := Registry{
+		"csv":    fakeSource("csv"),
+		"sqlite": errSource{errors.New("disk on fire")},
+		"db":     errSource{&ValidationError{Field: "Select", Value: "x", Reason: "unknown column"}},
+	}
+	tests := []struct {
+		target string
+		status int
+	}{
+		{"/data.parquet", http.StatusNotFound},
+		{"/data.csv?format=xml", http.StatusNotAcceptable},
+		{"/data.sqlite;users", http.StatusInternalServerError},
+		{"/data.db;users", http.StatusBadRequest},
+		{"/data.csv;id[abc:1]", http.StatusBadRequest},
+		{"/data.csv;id[1:2:3]", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		Handler(registry).ServeHTTP(rec, httptest.NewRequest("GET", tt.target, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.target, rec.Code, tt.status, rec.Body)
+		}
+		// Execution errors are logged, not sent to the client
+		if tt.status == http.StatusInternalServerError && strings.Contains(rec.Body.String(), "disk on fire") {
+			t.Errorf("%s: body %q leaks the execution error", tt.target, rec.Body)
+		}
+	}
+}
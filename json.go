@@ -0,0 +1,130 @@
+package banquet
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+// banquetJSON is the serialized form of a Banquet. Field names match bridge.BanquetDTO so
+// that JSON produced here and over FFI share one shape.
+type banquetJSON struct {
+	Scheme        string
+	Host          string
+	Where         string
+	Table         string
+	TableSource   TableSource `json:",omitempty"`
+	Schema        string      `json:",omitempty"`
+	Select        []string
+	SelectAll     bool     `json:",omitempty"`
+	Exclude       []string `json:",omitempty"`
+	Distinct      bool     `json:",omitempty"`
+	SortDirection string
+	Sorts         []OrderTerm `json:",omitempty"`
+	Conditions    []Condition `json:",omitempty"`
+	Limit         string
+	Offset        string
+	GroupBy       string
+	Having        string
+	OrderBy       string
+	RawSQL        string `json:",omitempty"`
+	DataSetPath   string
+	ColumnPath    string
+	OriginalURL   string
+	Warnings      []string     `json:",omitempty"`
+	OutputFormat  string       `json:",omitempty"`
+	Extra         []QueryParam `json:",omitempty"`
+	From          *Banquet     `json:",omitempty"`
+	FromEnd       bool         `json:",omitempty"`
+	SliceStart    string       `json:",omitempty"` // Raw bounds of a FromEnd slice, for ResolveFromEnd.
+	SliceEnd      string       `json:",omitempty"`
+	QueryWhere    string       `json:",omitempty"` // The where param as parsed, rendered ahead of Conditions.
+}
+
+// MarshalJSON emits the parsed clauses of b rather than the embedded url.URL internals.
+func (b *Banquet) MarshalJSON() ([]byte, error) {
+	v := banquetJSON{
+		Where:         b.Where,
+		Table:         b.Table,
+		TableSource:   b.TableSource,
+		Schema:        b.Schema,
+		Select:        b.Select,
+		SelectAll:     b.SelectAll,
+		Exclude:       b.Exclude,
+		Distinct:      b.Distinct,
+		SortDirection: b.SortDirection,
+		Sorts:         b.Sorts,
+		Conditions:    b.Conditions,
+		Limit:         b.Limit,
+		Offset:        b.Offset,
+		GroupBy:       b.GroupBy,
+		Having:        b.Having,
+		OrderBy:       b.OrderBy,
+		RawSQL:        b.RawSQL,
+		DataSetPath:   b.DataSetPath,
+		ColumnPath:    b.ColumnPath,
+		Warnings:      b.Warnings,
+		OutputFormat:  b.OutputFormat,
+		Extra:         b.Extra,
+		From:          b.From,
+		FromEnd:       b.FromEnd,
+		SliceStart:    b.sliceStart,
+		SliceEnd:      b.sliceEnd,
+		QueryWhere:    b.queryWhere,
+	}
+	if b.URL != nil {
+		v.Scheme = b.Scheme
+		v.Host = b.Host
+		v.OriginalURL = b.String()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON restores a Banquet written by MarshalJSON. The URL is rebuilt from OriginalURL
+// when present, otherwise from Scheme and Host.
+func (b *Banquet) UnmarshalJSON(data []byte) error {
+	var v banquetJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	u := &url.URL{Scheme: v.Scheme, Host: v.Host}
+	if v.OriginalURL != "" {
+		parsed, err := url.Parse(v.OriginalURL)
+		if err != nil {
+			return err
+		}
+		u = parsed
+	}
+	*b = Banquet{
+		URL:           u,
+		Where:         v.Where,
+		Table:         v.Table,
+		TableSource:   v.TableSource,
+		Schema:        v.Schema,
+		Select:        v.Select,
+		SelectAll:     v.SelectAll,
+		Exclude:       v.Exclude,
+		Distinct:      v.Distinct,
+		SortDirection: v.SortDirection,
+		Sorts:         v.Sorts,
+		Conditions:    v.Conditions,
+		Limit:         v.Limit,
+		Offset:        v.Offset,
+		GroupBy:       v.GroupBy,
+		Having:        v.Having,
+		OrderBy:       v.OrderBy,
+		RawSQL:        v.RawSQL,
+		DataSetPath:   v.DataSetPath,
+		ColumnPath:    v.ColumnPath,
+		Warnings:      v.Warnings,
+		OutputFormat:  v.OutputFormat,
+		Extra:         v.Extra,
+		From:          v.From,
+		FromEnd:       v.FromEnd,
+		sliceStart:    v.SliceStart,
+		sliceEnd:      v.SliceEnd,
+		queryWhere:    v.QueryWhere,
+		Auth:          parseAuth(u.User),
+		rawurl:        v.OriginalURL,
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package banquet
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	b, err := ParseBanquet("gs://user:pass@bucket/data.sqlite;users;id,name,-age,status!=active[10:20]?where=age>18&groupby=country&having=count(*)>5")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var got Banquet
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if got.Scheme != "gs" || got.Host != "bucket" {
+		t.Errorf("URL mismatch: got %s://%s", got.Scheme, got.Host)
+	}
+	if got.String() != b.String() {
+		t.Errorf("String mismatch: got %q, want %q", got.String(), b.String())
+	}
+	if got.Auth != b.Auth {
+		t.Errorf("Auth mismatch: got %+v, want %+v", got.Auth, b.Auth)
+	}
+	fields := []struct {
+		name      string
+		got, want any
+	}{
+		{"DataSetPath", got.DataSetPath, b.DataSetPath},
+		{"Table", got.Table, b.Table},
+		{"Select", got.Select, b.Select},
+		{"Where", got.Where, b.Where},
+		{"OrderBy", got.OrderBy, b.OrderBy},
+		{"SortDirection", got.SortDirection, b.SortDirection},
+		{"Sorts", got.Sorts, b.Sorts},
+		{"Conditions", got.Conditions, b.Conditions},
+		{"Limit", got.Limit, b.Limit},
+		{"Offset", got.Offset, b.Offset},
+		{"GroupBy", got.GroupBy, b.GroupBy},
+		{"Having", got.Having, b.Having},
+		{"ColumnPath", got.ColumnPath, b.ColumnPath},
+	}
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.got, f.want) {
+			t.Errorf("%s mismatch: got %v, want %v", f.name, f.got, f.want)
+		}
+	}
+}
+
+func TestJSONRoundTripSubquery(t *testing.T) {
+	inner, err := ParseBanquet("data.sqlite;orders;country?groupby=country&having=count>5")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	b := Subquery(inner, "busy")
+	b.Conditions = []Condition{{Column: "country", Operator: OpNe, Value: "nz"}}
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got Banquet
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got.From == nil {
+		t.Fatal("From lost in the round trip")
+	}
+	if got.Table != "busy" || got.From.Table != "orders" || got.From.Having != inner.Having {
+		t.Errorf("got Table %q From %+v, want busy over %+v", got.Table, got.From, inner)
+	}
+	if !reflect.DeepEqual(got.Conditions, b.Conditions) {
+		t.Errorf("Conditions = %+v, want %+v", got.Conditions, b.Conditions)
+	}
+}
+
+func TestJSONRoundTripFromEnd(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;users[-10:-2]")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got Banquet
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !got.FromEnd {
+		t.Fatal("FromEnd lost in the round trip")
+	}
+	got.ResolveFromEnd(100)
+	if got.Offset != "90" || got.Limit != "8" {
+		t.Errorf("ResolveFromEnd(100) after JSON = offset %q limit %q, want 90 and 8", got.Offset, got.Limit)
+	}
+}
+
+func TestJSONRoundTripParseOptions(t *testing.T) {
+	tests := []struct {
+		url  string
+		opts ParseOptions
+		want string
+	}{
+		{"data.sqlite;users;id,status=active?where=age>18--%20x", ParseOptions{StripComments: true}, `age>18 AND "status" = 'active'`},
+		{"data.sqlite;users;id,status=active?where=s%20in%20(a,b)", ParseOptions{QuoteInLists: true}, `s in ('a', 'b') AND "status" = 'active'`},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquetWithOptions(tt.url, tt.opts)
+		if err != nil {
+			t.Fatalf("ParseBanquetWithOptions(%q) failed: %v", tt.url, err)
+		}
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var got Banquet
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		// The where param must come back as parsed, not re-read from the URL without the options
+		if where := sqliteRenderer.Where(&got); where != tt.want {
+			t.Errorf("%q: Where after JSON = %q, want %q", tt.url, where, tt.want)
+		}
+		if err := Validate(&got); err != nil {
+			t.Errorf("%q: Validate after JSON = %v", tt.url, err)
+		}
+	}
+}
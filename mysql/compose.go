@@ -0,0 +1,198 @@
+package mysql
+
+// Separate package for the MySQL dialect so that callers who don't target MySQL
+// don't pull it in. Clause ordering mirrors the sqlite package.
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/darianmavgo/banquet"
+)
+
+// maxRows is the documented MySQL idiom for "no limit" when only an offset is wanted,
+// since MySQL does not accept OFFSET without LIMIT.
+const maxRows = "18446744073709551615"
+
+// Options controls MySQL specific output.
+type Options struct {
+	// ShortLimit emits the two-arg "LIMIT offset, count" form instead of "LIMIT count OFFSET offset".
+	ShortLimit bool
+
+	// Columns is the table's column list, used to expand * when the URL excludes columns (!col).
+	Columns []string
+
+	// Types declares column types so path condition values are quoted by type rather than
+	// by whether they look like numbers, as in sqlite.Options.
+	Types map[string]banquet.ColumnType
+
+	// bind, set by ComposeArgs, writes each quoted condition value as a ? placeholder and
+	// collects the value.
+	bind func(string) string
+}
+
+// Compose builds a MySQL query string from a Banquet struct using backtick quoted identifiers.
+func Compose(bq *banquet.Banquet) string {
+	return ComposeWithOptions(bq, Options{})
+}
+
+// ComposeWithOptions builds a MySQL query string from a Banquet struct honoring opts.
+func ComposeWithOptions(bq *banquet.Banquet, opts Options) string {
+	var parts []string
+
+	// SELECT
+	selectClause := "*"
+	selectCols, err := bq.ExpandSelect(opts.Columns)
+	if err != nil || len(bq.Exclude) > 0 && len(selectCols) == 0 {
+		// Exclusions can't be expanded without Options.Columns. MySQL rejects this
+		// placeholder, so excluded columns are never returned silently.
+		selectClause = "* EXCEPT (" + quoteList(bq.Exclude) + ")"
+	} else if len(selectCols) > 0 && selectCols[0] != "*" {
+		selectClause = quoteList(selectCols)
+	}
+	if bq.Distinct {
+		selectClause = "DISTINCT " + selectClause
+	}
+	parts = append(parts, "SELECT "+selectClause)
+
+	// FROM
+	if bq.From != nil {
+		parts = append(parts, "FROM ("+ComposeWithOptions(bq.From, opts)+") AS "+QuoteIdentifier(bq.Table))
+	} else {
+		table := bq.Table
+		if table == "" {
+			table = "tb0"
+		}
+		parts = append(parts, "FROM "+quoteTable(bq, table))
+	}
+
+	// WHERE
+	renderer := banquet.RendererFor(Dialect)
+	renderer.Types = opts.Types
+	if opts.bind != nil {
+		renderer.String = opts.bind
+	}
+	if where := renderer.Where(bq); where != "" {
+		parts = append(parts, "WHERE "+where)
+	}
+
+	// GROUP BY
+	if bq.GroupBy != "" {
+		parts = append(parts, "GROUP BY "+QuoteIdentifier(bq.GroupBy))
+	}
+
+	// HAVING
+	if bq.Having != "" {
+		parts = append(parts, "HAVING "+banquet.QuoteAggregates(bq.Having, QuoteIdentifier))
+	}
+
+	// ORDER BY
+	sorts := bq.Sorts
+	if len(sorts) == 0 && bq.OrderBy != "" {
+		sorts = []banquet.OrderTerm{{Column: bq.OrderBy, Direction: bq.SortDirection}}
+	}
+	if len(sorts) > 0 {
+		parts = append(parts, "ORDER BY "+banquet.RendererFor(Dialect).OrderBy(sorts))
+	}
+
+	// LIMIT / OFFSET
+	limit := bq.Limit
+	if limit == "" && bq.Offset != "" {
+		limit = maxRows
+	}
+	if limit != "" {
+		if opts.ShortLimit && bq.Offset != "" {
+			parts = append(parts, "LIMIT "+bq.Offset+", "+limit)
+		} else {
+			parts = append(parts, "LIMIT "+limit)
+			if bq.Offset != "" {
+				parts = append(parts, "OFFSET "+bq.Offset)
+			}
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ComposeArgs is the bound-args variant of Compose. The string values of path conditions,
+// LIMIT and OFFSET are emitted as ? placeholders and returned in args, in order. Numbers and
+// booleans stay inline, and the where and having params remain raw SQL fragments.
+func ComposeArgs(bq *banquet.Banquet) (string, []any) {
+	lenient := *bq
+	lenient.Limit, lenient.Offset = "", ""
+	var args []any
+	query := ComposeWithOptions(&lenient, Options{bind: func(val string) string {
+		args = append(args, val)
+		return "?"
+	}})
+
+	limit := bq.Limit
+	if limit == "" && bq.Offset != "" {
+		limit = maxRows
+	}
+	for _, clause := range []struct{ keyword, value string }{{"LIMIT", limit}, {"OFFSET", bq.Offset}} {
+		if clause.value == "" {
+			continue
+		}
+		var arg any = clause.value
+		if n, err := strconv.ParseUint(clause.value, 10, 64); err == nil {
+			arg = n
+		}
+		query += " " + clause.keyword + " ?"
+		args = append(args, arg)
+	}
+	return query, args
+}
+
+// quoteList quotes and comma joins cols, leaving a * next to explicit columns bare.
+func quoteList(cols []string) string {
+	quotedCols := make([]string, len(cols))
+	for i, col := range cols {
+		quotedCols[i] = col
+		if col != "*" {
+			quotedCols[i] = banquet.QuoteColumn(col, QuoteIdentifier)
+		}
+	}
+	return strings.Join(quotedCols, ", ")
+}
+
+// quoteTable quotes table, qualified by bq.Schema when set, e.g. `shop`.`users`.
+func quoteTable(bq *banquet.Banquet, table string) string {
+	if bq.Schema == "" || table == "" {
+		return QuoteIdentifier(table)
+	}
+	var parts []string
+	for _, name := range strings.Split(bq.Schema, ".") {
+		parts = append(parts, QuoteIdentifier(name))
+	}
+	return strings.Join(append(parts, QuoteIdentifier(table)), ".")
+}
+
+// Dialect is the MySQL spelling used to render path conditions.
+var Dialect banquet.Dialect = dialect{}
+
+type dialect struct{}
+
+func (dialect) QuoteIdentifier(s string) string { return QuoteIdentifier(s) }
+
+// NotEqual spells banquet.OpNe as !=.
+func (dialect) NotEqual() string { return "!=" }
+
+// NullsOrdering reports that MySQL has no NULLS FIRST/LAST, so it is emulated.
+func (dialect) NullsOrdering() bool { return false }
+
+// Like is LIKE, which is case-insensitive under the default collations.
+func (dialect) Like() string { return "LIKE" }
+
+// QuoteString single-quotes s, escaping backslashes since MySQL reads them as escapes.
+func (dialect) QuoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", "''").Replace(s) + "'"
+}
+
+// QuoteIdentifier wraps a string in backticks and escapes existing backticks by doubling them.
+func QuoteIdentifier(s string) string {
+	if s == "" || s == "*" {
+		return s
+	}
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
@@ -0,0 +1,235 @@
+package mysql
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/darianmavgo/banquet"
+)
+
+func TestCompose(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{
+			url:      "data.sqlite;users",
+			expected: "SELECT * FROM `users`",
+		},
+		{
+			url:      "data.sqlite;users;id,name",
+			expected: "SELECT `id`, `name` FROM `users`",
+		},
+		{
+			url:      "users.csv",
+			expected: "SELECT * FROM `tb0`",
+		},
+		{
+			url:      "data.sqlite;users;id,-age?where=active=1",
+			expected: "SELECT `id` FROM `users` WHERE active=1 ORDER BY `age` DESC",
+		},
+		{
+			url:      "data.sqlite;resumes;id,first%20name!=Bob",
+			expected: "SELECT `id` FROM `resumes` WHERE `first name` != 'Bob'",
+		},
+		{
+			url:      "data.sqlite;users[20:30]",
+			expected: "SELECT * FROM `users` LIMIT 10 OFFSET 20",
+		},
+		{
+			url:      "data.sqlite;orders?groupby=country&having=count(*)>5&having=sum(total)>100",
+			expected: "SELECT * FROM `orders` GROUP BY `country` HAVING count(*) > 5 AND sum(`total`) > 100",
+		},
+		{
+			// MySQL rejects OFFSET without LIMIT
+			url:      "data.sqlite;users?offset=5",
+			expected: "SELECT * FROM `users` LIMIT 18446744073709551615 OFFSET 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			bq, err := banquet.ParseBanquet(tt.url)
+			if err != nil {
+				t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+			}
+			got := Compose(bq)
+			if got != tt.expected {
+				t.Errorf("Compose() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestComposeShortLimit(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{
+			url:      "data.sqlite;users[20:30]",
+			expected: "SELECT * FROM `users` LIMIT 20, 10",
+		},
+		{
+			url:      "data.sqlite;users?limit=5",
+			expected: "SELECT * FROM `users` LIMIT 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			bq, err := banquet.ParseBanquet(tt.url)
+			if err != nil {
+				t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+			}
+			got := ComposeWithOptions(bq, Options{ShortLimit: true})
+			if got != tt.expected {
+				t.Errorf("ComposeWithOptions() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if got := QuoteIdentifier("we`ird"); got != "`we``ird`" {
+		t.Errorf("QuoteIdentifier() = %q, want %q", got, "`we``ird`")
+	}
+	if got := QuoteIdentifier("*"); got != "*" {
+		t.Errorf("QuoteIdentifier(*) = %q, want *", got)
+	}
+}
+
+func TestComposeArgs(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users[20:30]")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got, args := ComposeArgs(bq)
+	if want := "SELECT * FROM `users` LIMIT ? OFFSET ?"; got != want {
+		t.Errorf("ComposeArgs() = %q, want %q", got, want)
+	}
+	if len(args) != 2 || args[0] != uint64(10) || args[1] != uint64(20) {
+		t.Errorf("ComposeArgs() args = %v, want [10 20]", args)
+	}
+}
+
+func TestComposeSchemaQualified(t *testing.T) {
+	bq, err := banquet.ParseBanquet("db;shop.users;id")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := Compose(bq), "SELECT `id` FROM `shop`.`users`"; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+}
+
+func TestDialectNotEqual(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;status!=x")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	want := "`status` != 'x'"
+	if got := banquet.RendererFor(Dialect).Where(bq); got != want {
+		t.Errorf("Where = %q, want %q", got, want)
+	}
+	if got := Compose(bq); !strings.Contains(got, "WHERE "+want) {
+		t.Errorf("Compose() = %q, want WHERE %q", got, want)
+	}
+}
+
+func TestComposeContains(t *testing.T) {
+	bq, err := banquet.ParseBanquet("db;users;id,name~smith")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	want := " LIKE '%smith%'"
+	if got := Compose(bq); !strings.Contains(got, want) {
+		t.Errorf("Compose() = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestComposeBackslashLiteral(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{`db;users;name=%5C'%20OR%201=1%20--%20`, "SELECT * FROM `users` WHERE `name` = '\\\\'' OR 1=1 --'"},
+		{`db;users;name~%5C'%20OR%201=1%20--%20`, "SELECT * FROM `users` WHERE `name` LIKE '%\\\\'' OR 1=1 --%'"},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeAfterJSON(t *testing.T) {
+	bq, err := banquet.ParseBanquet("db;users;id,status!=banned,name~ann?where=age>18")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	data, err := json.Marshal(bq)
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	var got banquet.Banquet
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	want := "SELECT `id` FROM `users` WHERE age>18 AND `status` != 'banned' AND `name` LIKE '%ann%'"
+	if c := Compose(bq); c != want {
+		t.Errorf("Compose() = %q, want %q", c, want)
+	}
+	if c := Compose(&got); c != want {
+		t.Errorf("Compose(after JSON) = %q, want %q", c, want)
+	}
+}
+
+func TestComposeColumnTypes(t *testing.T) {
+	types := map[string]banquet.ColumnType{
+		"zip":    banquet.TypeText,
+		"code":   banquet.TypeNumeric,
+		"active": banquet.TypeBoolean,
+	}
+	bq, err := banquet.ParseBanquet("data.sqlite;users;zip=12345,code=007,active=true")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := ComposeWithOptions(bq, Options{Types: types}), "SELECT * FROM `users` WHERE `zip` = '12345' AND `code` = 007 AND `active` = TRUE"; got != want {
+		t.Errorf("ComposeWithOptions(Types) = %q, want %q", got, want)
+	}
+}
+
+func TestComposeSubqueryOptions(t *testing.T) {
+	// The caller's options apply inside a subquery as at the top level
+	inner, err := banquet.ParseBanquet("data.sqlite;users;!password,zip=12345")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	opts := Options{Types: map[string]banquet.ColumnType{"zip": banquet.TypeText}, Columns: []string{"id", "password", "zip"}}
+	if got, want := ComposeWithOptions(banquet.Subquery(inner, "u"), opts), "SELECT * FROM (SELECT `id`, `zip` FROM `users` WHERE `zip` = '12345') AS `u`"; got != want {
+		t.Errorf("ComposeWithOptions(subquery) = %q, want %q", got, want)
+	}
+}
+
+func TestComposeArgsConditions(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;id,status!=active,name~o'b_,age>30[0:5]")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got, args := ComposeArgs(bq)
+	if want := "SELECT `id` FROM `users` WHERE `status` != ? AND `name` LIKE ? ESCAPE '!' AND `age` > 30 LIMIT ? OFFSET ?"; got != want {
+		t.Errorf("ComposeArgs() = %q, want %q", got, want)
+	}
+	// Every string value is bound, none is left as a literal
+	if strings.Contains(strings.ReplaceAll(got, "ESCAPE '!'", ""), "'") {
+		t.Errorf("ComposeArgs() = %q has a value literal", got)
+	}
+	if want := []any{"active", "%o'b!_%", uint64(5), uint64(0)}; !slices.Equal(args, want) {
+		t.Errorf("ComposeArgs() args = %v, want %v", args, want)
+	}
+}
@@ -0,0 +1,177 @@
+package postgres
+
+// Separate package for the PostgreSQL dialect. Identifiers use standard double quotes
+// and bound args use numbered $N placeholders.
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/darianmavgo/banquet"
+)
+
+// Options controls PostgreSQL specific output.
+type Options struct {
+	// Types declares column types so path condition values are quoted by type rather than
+	// by whether they look like numbers, as in sqlite.Options.
+	Types map[string]banquet.ColumnType
+
+	// bind, set by ComposeArgs, writes each quoted condition value as a $N placeholder and
+	// collects the value.
+	bind func(string) string
+}
+
+// Compose builds a PostgreSQL query string from a Banquet struct.
+func Compose(bq *banquet.Banquet) string {
+	return ComposeWithOptions(bq, Options{})
+}
+
+// ComposeWithOptions builds a PostgreSQL query string from a Banquet struct honoring opts.
+func ComposeWithOptions(bq *banquet.Banquet, opts Options) string {
+	var parts []string
+
+	// SELECT
+	selectClause := "*"
+	selectCols, err := bq.ExpandSelect(nil)
+	if err != nil || len(bq.Exclude) > 0 && len(selectCols) == 0 {
+		// Exclusions can't be expanded without a column list. PostgreSQL rejects this
+		// placeholder, so excluded columns are never returned silently.
+		selectClause = "* EXCEPT (" + quoteList(bq.Exclude) + ")"
+	} else if len(selectCols) > 0 && selectCols[0] != "*" {
+		selectClause = quoteList(selectCols)
+	}
+	if bq.Distinct {
+		selectClause = "DISTINCT " + selectClause
+	}
+	parts = append(parts, "SELECT "+selectClause)
+
+	// FROM
+	if bq.From != nil {
+		parts = append(parts, "FROM ("+ComposeWithOptions(bq.From, opts)+") AS "+QuoteIdentifier(bq.Table))
+	} else {
+		table := bq.Table
+		if table == "" {
+			table = "tb0"
+		}
+		parts = append(parts, "FROM "+quoteTable(bq, table))
+	}
+
+	// WHERE
+	renderer := banquet.RendererFor(Dialect)
+	renderer.Types = opts.Types
+	if opts.bind != nil {
+		renderer.String = opts.bind
+	}
+	if where := renderer.Where(bq); where != "" {
+		parts = append(parts, "WHERE "+where)
+	}
+
+	// GROUP BY
+	if bq.GroupBy != "" {
+		parts = append(parts, "GROUP BY "+QuoteIdentifier(bq.GroupBy))
+	}
+
+	// HAVING
+	if bq.Having != "" {
+		parts = append(parts, "HAVING "+banquet.QuoteAggregates(bq.Having, QuoteIdentifier))
+	}
+
+	// ORDER BY
+	sorts := bq.Sorts
+	if len(sorts) == 0 && bq.OrderBy != "" {
+		sorts = []banquet.OrderTerm{{Column: bq.OrderBy, Direction: bq.SortDirection}}
+	}
+	if len(sorts) > 0 {
+		parts = append(parts, "ORDER BY "+banquet.RendererFor(Dialect).OrderBy(sorts))
+	}
+
+	// LIMIT
+	if bq.Limit != "" {
+		parts = append(parts, "LIMIT "+bq.Limit)
+	}
+
+	// OFFSET
+	if bq.Offset != "" {
+		parts = append(parts, "OFFSET "+bq.Offset)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ComposeArgs is the bound-args variant of Compose. The string values of path conditions,
+// LIMIT and OFFSET are emitted as $N placeholders and returned in args, $1 first. Numbers and
+// booleans stay inline, and the where and having params remain raw SQL fragments.
+func ComposeArgs(bq *banquet.Banquet) (string, []any) {
+	lenient := *bq
+	lenient.Limit, lenient.Offset = "", ""
+	var args []any
+	query := ComposeWithOptions(&lenient, Options{bind: func(val string) string {
+		args = append(args, val)
+		return "$" + strconv.Itoa(len(args))
+	}})
+
+	for _, clause := range []struct{ keyword, value string }{{"LIMIT", bq.Limit}, {"OFFSET", bq.Offset}} {
+		if clause.value == "" {
+			continue
+		}
+		var arg any = clause.value
+		if n, err := strconv.Atoi(clause.value); err == nil {
+			arg = n
+		}
+		args = append(args, arg)
+		query += " " + clause.keyword + " $" + strconv.Itoa(len(args))
+	}
+	return query, args
+}
+
+// quoteList quotes and comma joins cols, leaving a * next to explicit columns bare.
+func quoteList(cols []string) string {
+	quotedCols := make([]string, len(cols))
+	for i, col := range cols {
+		quotedCols[i] = col
+		if col != "*" {
+			quotedCols[i] = banquet.QuoteColumn(col, QuoteIdentifier)
+		}
+	}
+	return strings.Join(quotedCols, ", ")
+}
+
+// quoteTable quotes table, qualified by bq.Schema when set, e.g. "public"."users".
+func quoteTable(bq *banquet.Banquet, table string) string {
+	if bq.Schema == "" || table == "" {
+		return QuoteIdentifier(table)
+	}
+	var parts []string
+	for _, name := range strings.Split(bq.Schema, ".") {
+		parts = append(parts, QuoteIdentifier(name))
+	}
+	return strings.Join(append(parts, QuoteIdentifier(table)), ".")
+}
+
+// Dialect is the PostgreSQL spelling used to render path conditions.
+var Dialect banquet.Dialect = dialect{}
+
+type dialect struct{}
+
+func (dialect) QuoteIdentifier(s string) string { return QuoteIdentifier(s) }
+
+// NotEqual spells banquet.OpNe as the ANSI <>.
+func (dialect) NotEqual() string { return "<>" }
+
+// NullsOrdering reports that PostgreSQL accepts NULLS FIRST/LAST.
+func (dialect) NullsOrdering() bool { return true }
+
+// Like is ILIKE, since LIKE is case-sensitive in PostgreSQL.
+func (dialect) Like() string { return "ILIKE" }
+
+// QuoteString single-quotes s, doubling embedded single quotes; standard_conforming_strings
+// keeps backslashes literal.
+func (dialect) QuoteString(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+
+// QuoteIdentifier wraps a string in double quotes and escapes existing double quotes.
+func QuoteIdentifier(s string) string {
+	if s == "" || s == "*" {
+		return s
+	}
+	return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
+}
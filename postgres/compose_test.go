@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/darianmavgo/banquet"
+)
+
+func TestCompose(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{
+			url:      "data.db;users",
+			expected: "SELECT * FROM \"users\"",
+		},
+		{
+			url:      "data.db;users;id,name,-age?where=age>18",
+			expected: "SELECT \"id\", \"name\" FROM \"users\" WHERE age>18 ORDER BY \"age\" DESC",
+		},
+		{
+			url:      "data.db;users[10:20]",
+			expected: "SELECT * FROM \"users\" LIMIT 10 OFFSET 10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			bq, err := banquet.ParseBanquet(tt.url)
+			if err != nil {
+				t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+			}
+			got := Compose(bq)
+			if got != tt.expected {
+				t.Errorf("Compose() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestComposeArgs(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.db;users;id[10:30]")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got, args := ComposeArgs(bq)
+	if want := "SELECT \"id\" FROM \"users\" LIMIT $1 OFFSET $2"; got != want {
+		t.Errorf("ComposeArgs() = %q, want %q", got, want)
+	}
+	if len(args) != 2 || args[0] != 20 || args[1] != 10 {
+		t.Errorf("ComposeArgs() args = %v, want [20 10]", args)
+	}
+}
+
+func TestComposeSchemaQualified(t *testing.T) {
+	bq, err := banquet.ParseBanquet("db;public.users;id")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := Compose(bq), `SELECT "id" FROM "public"."users"`; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+}
+
+func TestDialectNotEqual(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;status!=x")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	want := `"status" <> 'x'`
+	if got := banquet.RendererFor(Dialect).Where(bq); got != want {
+		t.Errorf("Where = %q, want %q", got, want)
+	}
+	if got := Compose(bq); !strings.Contains(got, "WHERE "+want) {
+		t.Errorf("Compose() = %q, want WHERE %q", got, want)
+	}
+}
+
+func TestComposeNullsOrdering(t *testing.T) {
+	for url, want := range map[string]string{
+		"db;users;id,+name:nullsfirst":             `SELECT "id" FROM "users" ORDER BY "name" ASC NULLS FIRST`,
+		"db;users;id,-age:nullslast,+name":         `SELECT "id" FROM "users" ORDER BY "age" DESC NULLS LAST, "name" ASC`,
+		"db;users;id?orderby=name:desc:nullsfirst": `SELECT "id" FROM "users" ORDER BY "name" DESC NULLS FIRST`,
+	} {
+		bq, err := banquet.ParseBanquet(url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", url, err)
+		}
+		if got := Compose(bq); got != want {
+			t.Errorf("Compose(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func TestComposeSubquery(t *testing.T) {
+	inner, err := banquet.ParseBanquet("db;orders;country?groupby=country&having=sum(total)>100")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	outer := banquet.Subquery(inner, "big")
+	outer.Conditions = []banquet.Condition{{Column: "country", Operator: banquet.OpNe, Value: "us"}}
+	want := `SELECT * FROM (SELECT "country" FROM "orders" GROUP BY "country" HAVING sum("total") > 100) AS "big" WHERE "country" <> 'us'`
+	if got := Compose(outer); got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+}
+
+func TestComposeContains(t *testing.T) {
+	bq, err := banquet.ParseBanquet("db;users;id,name~smith")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	want := " ILIKE '%smith%'"
+	if got := Compose(bq); !strings.Contains(got, want) {
+		t.Errorf("Compose() = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestComposeColumnTypes(t *testing.T) {
+	types := map[string]banquet.ColumnType{
+		"zip":    banquet.TypeText,
+		"code":   banquet.TypeNumeric,
+		"active": banquet.TypeBoolean,
+	}
+	bq, err := banquet.ParseBanquet("data.sqlite;users;zip=12345,code=007,active=true")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := ComposeWithOptions(bq, Options{Types: types}), `SELECT * FROM "users" WHERE "zip" = '12345' AND "code" = 007 AND "active" = TRUE`; got != want {
+		t.Errorf("ComposeWithOptions(Types) = %q, want %q", got, want)
+	}
+}
+
+func TestComposeSubqueryOptions(t *testing.T) {
+	// The caller's options apply inside a subquery as at the top level
+	inner, err := banquet.ParseBanquet("data.sqlite;users;id,zip=12345")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	opts := Options{Types: map[string]banquet.ColumnType{"zip": banquet.TypeText}}
+	if got, want := ComposeWithOptions(banquet.Subquery(inner, "u"), opts), `SELECT * FROM (SELECT "id" FROM "users" WHERE "zip" = '12345') AS "u"`; got != want {
+		t.Errorf("ComposeWithOptions(subquery) = %q, want %q", got, want)
+	}
+}
+
+func TestComposeArgsConditions(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.db;users;id,status!=active,name~o'b_,age>30[0:5]")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got, args := ComposeArgs(bq)
+	if want := `SELECT "id" FROM "users" WHERE "status" <> $1 AND "name" ILIKE $2 ESCAPE '!' AND "age" > 30 LIMIT $3 OFFSET $4`; got != want {
+		t.Errorf("ComposeArgs() = %q, want %q", got, want)
+	}
+	// Every string value is bound, none is left as a literal
+	if strings.Contains(strings.ReplaceAll(got, "ESCAPE '!'", ""), "'") {
+		t.Errorf("ComposeArgs() = %q has a value literal", got)
+	}
+	if want := []any{"active", "%o'b!_%", 5, 0}; !slices.Equal(args, want) {
+		t.Errorf("ComposeArgs() args = %v, want %v", args, want)
+	}
+}
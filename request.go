@@ -0,0 +1,53 @@
+package banquet
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FromRequest parses the Banquet addressed by an incoming HTTP request.
+// It rebuilds the outer URL (scheme, host, path, query) from r and applies ParseNested semantics,
+// so both direct requests (/data.csv/col) and nested envelopes (/gs:/bucket/file.csv) are handled.
+// The inner URL is parsed with ParseBanquet and its error, e.g. a malformed slice, is returned.
+// When the inner URL carries no userinfo, credentials from the Authorization header are merged in.
+func FromRequest(r *http.Request) (*Banquet, error) {
+	outer := *r.URL
+	if !outer.IsAbs() {
+		outer.Scheme = "http"
+		if r.TLS != nil {
+			outer.Scheme = "https"
+		}
+		outer.Host = r.Host
+	}
+
+	inner, err := peelEnvelope(outer.String())
+	if err != nil {
+		return nil, err
+	}
+	b, err := ParseBanquet(inner)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.User == nil {
+		if user, pass, ok := r.BasicAuth(); ok {
+			b.User = url.UserPassword(user, pass)
+		} else if token, ok := bearerToken(r); ok {
+			b.User = url.User(token)
+		}
+		b.Auth = parseAuth(b.User)
+	}
+	return b, nil
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[len(prefix):])
+	return token, token != ""
+}
@@ -0,0 +1,85 @@
+package banquet
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFromRequestDirect(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://localhost:8080/data.sqlite/users/id,name?limit=5", nil)
+	b, err := FromRequest(r)
+	if err != nil {
+		t.Fatalf("FromRequest failed: %v", err)
+	}
+	if b.DataSetPath != "data.sqlite" {
+		t.Errorf("Expected DataSetPath 'data.sqlite', got %q", b.DataSetPath)
+	}
+	if b.Table != "users" {
+		t.Errorf("Expected Table 'users', got %q", b.Table)
+	}
+	if len(b.Select) != 2 || b.Select[0] != "id" || b.Select[1] != "name" {
+		t.Errorf("Expected Select [id name], got %v", b.Select)
+	}
+	if b.Limit != "5" {
+		t.Errorf("Expected Limit '5', got %q", b.Limit)
+	}
+}
+
+func TestFromRequestNested(t *testing.T) {
+	r := httptest.NewRequest("GET", "https://localhost:8080/gs:/matrix@bucket.appspot.com:8080/some/file/path.csv/column1,column2/+column3?orderid=1", nil)
+	b, err := FromRequest(r)
+	if err != nil {
+		t.Fatalf("FromRequest failed: %v", err)
+	}
+	if b.Scheme != "gs" {
+		t.Errorf("Expected Scheme 'gs', got %q", b.Scheme)
+	}
+	if b.Host != "bucket.appspot.com:8080" {
+		t.Errorf("Expected Host 'bucket.appspot.com:8080', got %q", b.Host)
+	}
+	if b.User.Username() != "matrix" {
+		t.Errorf("Expected Username 'matrix', got %q", b.User.Username())
+	}
+	if b.OrderBy != "column3" {
+		t.Errorf("Expected OrderBy 'column3', got %q", b.OrderBy)
+	}
+}
+
+func TestFromRequestAuthHeader(t *testing.T) {
+	r := httptest.NewRequest("GET", "/gs:/bucket/file.csv", nil)
+	r.SetBasicAuth("user", "pass")
+	b, err := FromRequest(r)
+	if err != nil {
+		t.Fatalf("FromRequest failed: %v", err)
+	}
+	if b.User == nil || b.User.Username() != "user" {
+		t.Fatalf("Expected Username 'user', got %v", b.User)
+	}
+	if p, _ := b.User.Password(); p != "pass" {
+		t.Errorf("Expected Password 'pass', got %q", p)
+	}
+
+	r = httptest.NewRequest("GET", "/gs:/bucket/file.csv", nil)
+	r.Header.Set("Authorization", "Bearer eyJtoken")
+	b, err = FromRequest(r)
+	if err != nil {
+		t.Fatalf("FromRequest failed: %v", err)
+	}
+	if b.User == nil || b.User.Username() != "eyJtoken" {
+		t.Errorf("Expected bearer token in userinfo, got %v", b.User)
+	}
+	if b.Auth.Token != "eyJtoken" {
+		t.Errorf("Expected Auth.Token 'eyJtoken', got %q", b.Auth.Token)
+	}
+
+	// Userinfo in the URL takes precedence over the header
+	r = httptest.NewRequest("GET", "/gs:/matrix@bucket/file.csv", nil)
+	r.SetBasicAuth("user", "pass")
+	b, err = FromRequest(r)
+	if err != nil {
+		t.Fatalf("FromRequest failed: %v", err)
+	}
+	if b.User.Username() != "matrix" {
+		t.Errorf("Expected Username 'matrix', got %q", b.User.Username())
+	}
+}
@@ -0,0 +1,80 @@
+package banquet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Rows is the tabular result of executing a Banquet against a DataSource.
+type Rows struct {
+	Columns []string
+	Values  [][]any
+}
+
+// DataSource answers Banquet queries for one kind of dataset, e.g. csv files or a sqlite database.
+type DataSource interface {
+	Execute(ctx context.Context, bq *Banquet) (*Rows, error)
+}
+
+// ErrNoSource is returned by Execute when no DataSource is registered for the URL.
+var ErrNoSource = errors.New("banquet: no data source registered")
+
+// Registry maps URL schemes and dataset extensions to the DataSource that serves them.
+// Keys are case-insensitive and may carry a leading dot.
+type Registry map[string]DataSource
+
+// Register makes src handle URLs whose scheme (e.g. "gs") or dataset extension
+// (e.g. "csv" or ".csv") matches key. Registering a key again replaces its source.
+func (r Registry) Register(key string, src DataSource) {
+	r[sourceKey(key)] = src
+}
+
+// Resolve returns the DataSource for bq, preferring a match on the URL scheme
+// over one on the DataSetPath extension.
+func (r Registry) Resolve(bq *Banquet) (DataSource, error) {
+	if bq.URL != nil && bq.Scheme != "" {
+		if src, ok := r[sourceKey(bq.Scheme)]; ok {
+			return src, nil
+		}
+	}
+	if ext := path.Ext(bq.DataSetPath); ext != "" {
+		if src, ok := r[sourceKey(ext)]; ok {
+			return src, nil
+		}
+	}
+	return nil, fmt.Errorf("%w for %q", ErrNoSource, bq.DataSetPath)
+}
+
+// sources is the package registry used by RegisterSource, ResolveSource and Execute.
+var sources = Registry{}
+
+// RegisterSource registers src in the package registry, see Registry.Register. Like
+// RegisterExtension, it is intended to be called during initialization.
+func RegisterSource(key string, src DataSource) {
+	sources.Register(key, src)
+}
+
+// ResolveSource returns the DataSource for bq from the package registry, see Registry.Resolve.
+func ResolveSource(bq *Banquet) (DataSource, error) {
+	return sources.Resolve(bq)
+}
+
+// Execute parses rawurl, resolves its DataSource and runs the query.
+func Execute(ctx context.Context, rawurl string) (*Rows, error) {
+	bq, err := ParseBanquetContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	src, err := ResolveSource(bq)
+	if err != nil {
+		return nil, err
+	}
+	return src.Execute(ctx, bq)
+}
+
+func sourceKey(key string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(key)), ".")
+}
@@ -0,0 +1,45 @@
+package banquet
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeSource answers every query with its name so tests can tell which source ran.
+type fakeSource string
+
+func (f fakeSource) Execute(ctx context.Context, bq *Banquet) (*Rows, error) {
+	return &Rows{Columns: []string{"source", "table"}, Values: [][]any{{string(f), bq.Table}}}, nil
+}
+
+func TestExecuteResolvesSource(t *testing.T) {
+	defer func() { sources = Registry{} }()
+	RegisterSource("csv", fakeSource("csv"))
+	RegisterSource(".SQLite", fakeSource("sqlite"))
+	RegisterSource("gs", fakeSource("gcs"))
+
+	tests := []struct {
+		url    string
+		source string
+	}{
+		{"data/users.csv;id", "csv"},
+		{"data.sqlite;users", "sqlite"},
+		{"data.SQLITE;users", "sqlite"},
+		// The scheme wins over the extension
+		{"gs://bucket/users.csv", "gcs"},
+	}
+	for _, tt := range tests {
+		rows, err := Execute(context.Background(), tt.url)
+		if err != nil {
+			t.Fatalf("Execute(%q) error: %v", tt.url, err)
+		}
+		if got := rows.Values[0][0]; got != tt.source {
+			t.Errorf("Execute(%q) ran source %v, want %q", tt.url, got, tt.source)
+		}
+	}
+
+	if _, err := Execute(context.Background(), "data.parquet"); !errors.Is(err, ErrNoSource) {
+		t.Errorf("Expected ErrNoSource for an unregistered extension, got %v", err)
+	}
+}
@@ -6,62 +6,167 @@ package sqlite
 // package sqliter
 
 import (
+	"context"
+	"database/sql"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/darianmavgo/banquet"
 )
 
+// Options controls optional output of the sqlite composer.
+type Options struct {
+	// FragmentComment appends the URL #fragment as a trailing "-- fragment" SQL comment
+	// so generated queries can be traced back to the originating request in DB logs.
+	FragmentComment bool
+
+	// Columns is the table's column list, used to expand * when the URL excludes columns (!col).
+	Columns []string
+
+	// QuoteStyle picks how identifiers are quoted; the zero value is SQLite's double quotes.
+	QuoteStyle QuoteStyle
+
+	// AllowRawSQL returns the rawsql query param as the query, ignoring every other clause.
+	// Only enable it for trusted callers: the SQL is neither checked nor quoted. Without it
+	// rawsql is ignored, and ComposeStrictWithOptions fails with banquet.ErrRawSQL.
+	AllowRawSQL bool
+
+	// KeywordCase sets the case of SQL keywords; the zero value is upper case. Quoted
+	// identifiers, string literals and the fragment comment are left as they are.
+	KeywordCase KeywordCase
+
+	// Types declares column types so path condition values are quoted by type rather than
+	// by whether they look like numbers, e.g. an active column of banquet.TypeBoolean
+	// renders active=true as TRUE. Columns not listed keep the guess.
+	Types map[string]banquet.ColumnType
+
+	// bind, set by ComposeArgs, writes each quoted condition value as a ? placeholder and
+	// collects the value.
+	bind func(string) string
+}
+
+// KeywordCase is the keyword casing used by ComposeWithOptions.
+type KeywordCase int
+
+const (
+	// KeywordUpper renders SELECT ... FROM ... WHERE.
+	KeywordUpper KeywordCase = iota
+	// KeywordLower renders select ... from ... where.
+	KeywordLower
+)
+
+// QuoteStyle is the identifier quoting used by ComposeWithOptions.
+type QuoteStyle int
+
+const (
+	// QuoteDouble quotes identifiers as "col", see QuoteIdentifier.
+	QuoteDouble QuoteStyle = iota
+	// QuoteBracket quotes identifiers as [col], the T-SQL form SQLite also accepts.
+	QuoteBracket
+	// QuoteNone leaves trusted identifiers bare, as in SELECT id, name FROM users. Names that
+	// fail banquet.ValidateIdentifier or aren't plain [A-Za-z_][A-Za-z0-9_]* are still double
+	// quoted, so this can't open an injection. Keywords used as names are the caller's concern.
+	QuoteNone
+)
+
+// plainIdentifier matches the names QuoteNone leaves unquoted.
+var plainIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Quote quotes s in style q. Like QuoteIdentifier it leaves "" and * as they are.
+func (q QuoteStyle) Quote(s string) string {
+	switch {
+	case s == "" || s == "*":
+		return s
+	case q == QuoteBracket:
+		return "[" + strings.ReplaceAll(s, "]", "]]") + "]"
+	case q == QuoteNone && banquet.ValidateIdentifier(s) == nil && plainIdentifier.MatchString(s):
+		return s
+	}
+	return QuoteIdentifier(s)
+}
+
 // Compose builds a SQL query string from a Banquet struct.
 // This implementation uses double-quoting for identifiers to prevent basic SQL injection
 // and handle reserved words/spaces in names.
 func Compose(bq *banquet.Banquet) string {
+	return ComposeWithOptions(bq, Options{})
+}
+
+// ComposeWithOptions builds a SQL query string from a Banquet struct honoring opts.
+func ComposeWithOptions(bq *banquet.Banquet, opts Options) string {
+	if opts.AllowRawSQL && bq.RawSQL != "" {
+		return bq.RawSQL
+	}
+	if bq.Table == banquet.TablesTable {
+		bq = listTables(bq)
+	}
 	var parts []string
+	quote := opts.QuoteStyle.Quote
 
 	// SELECT
 	selectClause := "*"
-	if len(bq.Select) > 0 && bq.Select[0] != "*" {
-		quotedCols := make([]string, len(bq.Select))
-		for i, col := range bq.Select {
-			quotedCols[i] = QuoteIdentifier(col)
-		}
-		selectClause = strings.Join(quotedCols, ", ")
+	selectCols, err := bq.ExpandSelect(opts.Columns)
+	if err != nil || len(bq.Exclude) > 0 && len(selectCols) == 0 {
+		// Exclusions can't be expanded without Options.Columns. SQLite rejects this
+		// placeholder, so excluded columns are never returned silently.
+		selectClause = "* EXCEPT (" + quoteList(bq.Exclude, quote) + ")"
+	} else if len(selectCols) > 0 && selectCols[0] != "*" {
+		selectClause = quoteList(selectCols, quote)
+	}
+	if bq.Distinct {
+		selectClause = "DISTINCT " + selectClause
 	}
 	parts = append(parts, "SELECT "+selectClause)
 
-	// FROM
-	table := bq.Table
-	if table == "" {
-		table = InferTable(bq)
+	// FROM, a subquery is composed without the fragment comment that would swallow the outer clauses
+	if bq.From != nil {
+		// The fragment comment and keyword case apply once, to the whole query
+		inner := ComposeWithOptions(bq.From, Options{Columns: opts.Columns, QuoteStyle: opts.QuoteStyle, AllowRawSQL: opts.AllowRawSQL, Types: opts.Types, bind: opts.bind})
+		parts = append(parts, "FROM "+derivedTable(inner, bq.Table, quote))
+	} else {
+		table := bq.Table
+		if table == "" {
+			table = InferTable(bq)
+		}
+		parts = append(parts, "FROM "+quoteTable(bq, table, quote))
 	}
-	parts = append(parts, "FROM "+QuoteIdentifier(table))
 
 	// WHERE
-	if bq.Where != "" {
-		parts = append(parts, "WHERE "+bq.Where)
+	renderer := banquet.RendererFor(dialect{opts.QuoteStyle})
+	renderer.Types = opts.Types
+	if opts.bind != nil {
+		renderer.String = opts.bind
+	}
+	if where := renderer.Where(bq); where != "" {
+		parts = append(parts, "WHERE "+where)
 	}
 
 	// GROUP BY
 	if bq.GroupBy != "" {
-		parts = append(parts, "GROUP BY "+QuoteIdentifier(bq.GroupBy))
+		parts = append(parts, "GROUP BY "+quote(bq.GroupBy))
 	}
 
 	// HAVING
 	if bq.Having != "" {
-		parts = append(parts, "HAVING "+bq.Having)
+		parts = append(parts, "HAVING "+banquet.QuoteAggregates(bq.Having, quote))
 	}
 
 	// ORDER BY
-	if bq.OrderBy != "" {
-		orderBy := QuoteIdentifier(bq.OrderBy)
-		if bq.SortDirection != "" {
-			orderBy += " " + bq.SortDirection
-		}
-		parts = append(parts, "ORDER BY "+orderBy)
+	sorts := bq.Sorts
+	if len(sorts) == 0 && bq.OrderBy != "" {
+		sorts = []banquet.OrderTerm{{Column: bq.OrderBy, Direction: bq.SortDirection}}
+	}
+	if len(sorts) > 0 {
+		parts = append(parts, "ORDER BY "+banquet.RendererFor(dialect{opts.QuoteStyle}).OrderBy(sorts))
 	}
 
-	// LIMIT
+	// LIMIT, with -1 (no limit) when only an offset is set since SQLite needs LIMIT before OFFSET
 	if bq.Limit != "" {
 		parts = append(parts, "LIMIT "+bq.Limit)
+	} else if bq.Offset != "" {
+		parts = append(parts, "LIMIT -1")
 	}
 
 	// OFFSET
@@ -69,9 +174,263 @@ func Compose(bq *banquet.Banquet) string {
 		parts = append(parts, "OFFSET "+bq.Offset)
 	}
 
+	if opts.KeywordCase == KeywordLower {
+		parts = []string{lowerKeywords(strings.Join(parts, " "))}
+	}
+
+	// Fragment comment
+	if opts.FragmentComment && bq.URL != nil {
+		if comment := sanitizeComment(bq.Fragment); comment != "" {
+			parts = append(parts, "-- "+comment)
+		}
+	}
+
 	return strings.Join(parts, " ")
 }
 
+// keywords are the upper case words the composer and banquet.Renderer emit, plus those
+// common in where and having fragments.
+var keywords = map[string]bool{
+	"SELECT": true, "DISTINCT": true, "FROM": true, "WHERE": true, "GROUP": true, "BY": true,
+	"HAVING": true, "ORDER": true, "ASC": true, "DESC": true, "NULLS": true, "FIRST": true,
+	"LAST": true, "LIMIT": true, "OFFSET": true, "EXCEPT": true, "AND": true, "OR": true,
+	"NOT": true, "IN": true, "IS": true, "NULL": true, "BETWEEN": true, "LIKE": true,
+	"ESCAPE": true, "TRUE": true, "FALSE": true, "CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
+}
+
+// lowerKeywords lower cases the keywords in query, skipping quoted identifiers ("col", [col],
+// `col`) and string literals. Only words written in upper case are keywords, so a bare
+// identifier such as Status keeps its case.
+func lowerKeywords(query string) string {
+	var out strings.Builder
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			j := i + 1
+			for j < len(query) {
+				if query[j] == end {
+					// A doubled quote is an escaped quote
+					if end != ']' && j+1 < len(query) && query[j+1] == end {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			j = min(j+1, len(query))
+			out.WriteString(query[i:j])
+			i = j
+		case c == '_' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z':
+			j := i + 1
+			for j < len(query) && (query[j] == '_' || 'A' <= query[j] && query[j] <= 'Z' || 'a' <= query[j] && query[j] <= 'z' || '0' <= query[j] && query[j] <= '9') {
+				j++
+			}
+			if word := query[i:j]; keywords[word] {
+				out.WriteString(strings.ToLower(word))
+			} else {
+				out.WriteString(word)
+			}
+			i = j
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// listTables rewrites a banquet.TablesTable query against sqlite_master, keeping only rows of
+// type table and selecting their name unless columns were given.
+func listTables(bq *banquet.Banquet) *banquet.Banquet {
+	catalog := *bq
+	catalog.Table, catalog.Schema = "sqlite_master", ""
+	if len(bq.Select) == 0 || len(bq.Select) == 1 && bq.Select[0] == "*" && !bq.SelectAll {
+		catalog.Select = []string{"name"}
+	}
+	catalog.Conditions = append([]banquet.Condition{{Column: "type", Operator: banquet.OpEq, Value: "table"}}, bq.Conditions...)
+	return &catalog
+}
+
+// sanitizeComment flattens line breaks so s cannot end a "--" comment and smuggle in SQL.
+func sanitizeComment(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '\r', '\n', '\v', '\f', '\u0085', '\u2028', '\u2029':
+			return ' '
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+// ComposeArgs is the bound-args variant of Compose. The string values of path conditions
+// (status!=active), LIMIT and OFFSET are emitted as ? placeholders and returned in args, in
+// order. Numbers and booleans stay inline, and the where and having params remain raw SQL
+// fragments.
+func ComposeArgs(bq *banquet.Banquet) (string, []any) {
+	lenient := *bq
+	lenient.Limit, lenient.Offset = "", ""
+	var args []any
+	query := ComposeWithOptions(&lenient, Options{bind: func(val string) string {
+		args = append(args, val)
+		return "?"
+	}})
+
+	limit := bq.Limit
+	if limit == "" && bq.Offset != "" {
+		limit = "-1"
+	}
+	for _, clause := range []struct{ keyword, value string }{{"LIMIT", limit}, {"OFFSET", bq.Offset}} {
+		if clause.value == "" {
+			continue
+		}
+		var arg any = clause.value
+		if n, err := strconv.Atoi(clause.value); err == nil {
+			arg = n
+		}
+		query += " " + clause.keyword + " ?"
+		args = append(args, arg)
+	}
+	return query, args
+}
+
+// Query composes bq with ComposeArgs and executes it against db.
+func Query(db *sql.DB, bq *banquet.Banquet) (*sql.Rows, error) {
+	return QueryContext(context.Background(), db, bq)
+}
+
+// QueryContext is like Query but honors ctx.
+func QueryContext(ctx context.Context, db *sql.DB, bq *banquet.Banquet) (*sql.Rows, error) {
+	query, args := ComposeArgs(bq)
+	return db.QueryContext(ctx, query, args...)
+}
+
+// Source is a banquet.DataSource backed by an open sqlite database.
+type Source struct {
+	DB *sql.DB
+}
+
+// Execute implements banquet.DataSource by running the composed query against s.DB.
+func (s Source) Execute(ctx context.Context, bq *banquet.Banquet) (*banquet.Rows, error) {
+	rows, err := QueryContext(ctx, s.DB, bq)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &banquet.Rows{Columns: columns}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		result.Values = append(result.Values, values)
+	}
+	return result, rows.Err()
+}
+
+// ComposeErr is like Compose but returns banquet.ErrNoTable when bq names no table and
+// InferTable finds none, where Compose would emit SELECT ... FROM with no table.
+func ComposeErr(bq *banquet.Banquet) (string, error) {
+	if bq.Table == "" && InferTable(bq) == "" {
+		return "", banquet.ErrNoTable
+	}
+	return Compose(bq), nil
+}
+
+// ComposeStrict validates bq with banquet.Validate before composing,
+// returning the validation error instead of SQL when any identifier or fragment is unsafe.
+// Exclusions from * can't be expanded here and return banquet.ErrNoColumns, and a slice
+// counting from the end that hasn't been through ResolveFromEnd returns banquet.ErrUnresolvedSlice.
+func ComposeStrict(bq *banquet.Banquet) (string, error) {
+	return ComposeStrictWithOptions(bq, Options{})
+}
+
+// ComposeStrictWithOptions is like ComposeStrict but honors opts. A rawsql query param fails
+// with banquet.ErrRawSQL unless opts.AllowRawSQL is set, in which case it is returned unchecked.
+func ComposeStrictWithOptions(bq *banquet.Banquet, opts Options) (string, error) {
+	if bq.RawSQL != "" {
+		if !opts.AllowRawSQL {
+			return "", banquet.ErrRawSQL
+		}
+		return bq.RawSQL, nil
+	}
+	if err := banquet.Validate(bq); err != nil {
+		return "", err
+	}
+	if _, err := bq.ExpandSelect(opts.Columns); err != nil {
+		return "", err
+	}
+	if bq.FromEnd {
+		return "", banquet.ErrUnresolvedSlice
+	}
+	return ComposeWithOptions(bq, opts), nil
+}
+
+// quoteList quotes and comma joins cols, leaving a * next to explicit columns bare.
+func quoteList(cols []string, quote func(string) string) string {
+	quotedCols := make([]string, len(cols))
+	for i, col := range cols {
+		quotedCols[i] = col
+		if col != "*" {
+			quotedCols[i] = banquet.QuoteColumn(col, quote)
+		}
+	}
+	return strings.Join(quotedCols, ", ")
+}
+
+// derivedTable parenthesizes the subquery inner, followed by AS alias when one is set.
+func derivedTable(inner, alias string, quote func(string) string) string {
+	if alias == "" {
+		return "(" + inner + ")"
+	}
+	return "(" + inner + ") AS " + quote(alias)
+}
+
+// quoteTable quotes table, qualified by bq.Schema when set, e.g. "main"."users".
+func quoteTable(bq *banquet.Banquet, table string, quote func(string) string) string {
+	if bq.Schema == "" || table == "" {
+		return quote(table)
+	}
+	var parts []string
+	for _, name := range strings.Split(bq.Schema, ".") {
+		parts = append(parts, quote(name))
+	}
+	return strings.Join(append(parts, quote(table)), ".")
+}
+
+// Dialect is the SQLite spelling used to render path conditions.
+var Dialect banquet.Dialect = dialect{}
+
+type dialect struct{ style QuoteStyle }
+
+func (d dialect) QuoteIdentifier(s string) string { return d.style.Quote(s) }
+
+// NotEqual spells banquet.OpNe as != (SQLite also accepts <>).
+func (dialect) NotEqual() string { return "!=" }
+
+// NullsOrdering is false: NULLS FIRST/LAST needs SQLite 3.30, so it is emulated for older versions.
+func (dialect) NullsOrdering() bool { return false }
+
+// Like is LIKE, which SQLite already matches case-insensitively for ASCII.
+func (dialect) Like() string { return "LIKE" }
+
+// QuoteString single-quotes s, doubling embedded single quotes.
+func (dialect) QuoteString(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+
 // QuoteIdentifier wraps a string in double quotes and escapes existing double quotes.
 func QuoteIdentifier(s string) string {
 	if s == "" || s == "*" {
@@ -80,18 +439,64 @@ func QuoteIdentifier(s string) string {
 	return "\"" + strings.ReplaceAll(s, "\"", "\"\"") + "\""
 }
 
+// DefaultTable is the implicit table name for flat files such as csv.
+const DefaultTable = "tb0"
+
+// TableConfig controls the implicit table name InferTableWithConfig uses for flat files.
+type TableConfig struct {
+	// Name is used as is when set.
+	Name string
+	// UseFileStem names the table after the dataset file, e.g. users.csv -> users.
+	UseFileStem bool
+}
+
 // InferTable attempts to deduce the table name when one is not explicitly provided.
-// It checks the DataSetPath extension and whether columns were requested.
+// It checks the DataSetPath extension and whether columns were requested: a bare sqlite
+// database (data.sqlite) maps to sqlite_master, but one with columns and no table
+// (data.sqlite;;id,name) returns "" since no table can be inferred.
 func InferTable(bq *banquet.Banquet) string {
+	return InferTableWithConfig(bq, TableConfig{})
+}
+
+// ApplyInferredTable sets an empty bq.Table to InferTableWithConfig(bq, cfg) and marks it
+// banquet.TableInferredDefault, so callers can tell a guessed table from one the URL named.
+// It returns the table.
+func ApplyInferredTable(bq *banquet.Banquet, cfg TableConfig) string {
+	if bq.Table == "" {
+		if table := InferTableWithConfig(bq, cfg); table != "" {
+			bq.Table, bq.TableSource = table, banquet.TableInferredDefault
+		}
+	}
+	return bq.Table
+}
+
+// InferTableWithConfig is like InferTable but names the implicit flat file table per cfg
+// instead of DefaultTable. Set bq.Table to the result before composing to use it.
+// Extensions match in any case (DATA.SQLITE), while a file stem keeps its casing.
+func InferTableWithConfig(bq *banquet.Banquet, cfg TableConfig) string {
 	if bq.Table != "" {
 		return bq.Table
 	}
 
 	lower := strings.ToLower(bq.DataSetPath)
 	if strings.HasSuffix(lower, ".sqlite") || strings.HasSuffix(lower, ".db") {
+		// A bare database lists its tables via sqlite_master, but columns without a table
+		// almost never mean the schema table, so return no table rather than guess.
+		if len(bq.Select) > 0 && bq.Select[0] != "*" {
+			return ""
+		}
 		return "sqlite_master"
 	}
 
 	// Default fallback for flat files or if columns are specified but table is implicit
-	return "tb0"
+	switch {
+	case cfg.Name != "":
+		return cfg.Name
+	case cfg.UseFileStem:
+		base := path.Base(bq.DataSetPath)
+		if stem := strings.TrimSuffix(base, path.Ext(base)); stem != "" && stem != "." && stem != "/" {
+			return stem
+		}
+	}
+	return DefaultTable
 }
@@ -1,6 +1,9 @@
 package sqlite
 
 import (
+	"errors"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/darianmavgo/banquet"
@@ -23,6 +26,11 @@ func TestConstructSQL(t *testing.T) {
 			url:      "data.sqlite",
 			expected: "SELECT * FROM \"sqlite_master\"",
 		},
+		{
+			// Columns without a table don't target sqlite_master; the empty table leaves invalid SQL.
+			url:      "data.sqlite;;id,name",
+			expected: "SELECT \"id\", \"name\" FROM ",
+		},
 		{
 			// Explicit table "users"
 			url:      "data.sqlite;users",
@@ -72,6 +80,26 @@ func TestConstructSQL(t *testing.T) {
 			url:      "data.sqlite;users;id,-age,email",
 			expected: "SELECT \"id\", \"email\" FROM \"users\" ORDER BY \"age\" DESC",
 		},
+		{
+			// Query param direction via suffix
+			url:      "data.sqlite;users?orderby=name:desc",
+			expected: "SELECT * FROM \"users\" ORDER BY \"name\" DESC",
+		},
+		{
+			// Query param direction via prefix
+			url:      "data.sqlite;users?orderby=-name",
+			expected: "SELECT * FROM \"users\" ORDER BY \"name\" DESC",
+		},
+		{
+			// Query param multi-column
+			url:      "data.sqlite;users?orderby=lastname:asc,firstname:desc",
+			expected: "SELECT * FROM \"users\" ORDER BY \"lastname\" ASC, \"firstname\" DESC",
+		},
+		{
+			// Path multi-column is equivalent to the query param form
+			url:      "data.sqlite;users;+lastname,-firstname",
+			expected: "SELECT * FROM \"users\" ORDER BY \"lastname\" ASC, \"firstname\" DESC",
+		},
 
 		// --- 4. Slice Notation (Limit/Offset) ---
 		{
@@ -94,6 +122,21 @@ func TestConstructSQL(t *testing.T) {
 			url:      "data.sqlite;users;id,name[0:50]",
 			expected: "SELECT \"id\", \"name\" FROM \"users\" LIMIT 50 OFFSET 0",
 		},
+		{
+			// Single value slice is a limit
+			url:      "data.sqlite;users[10]",
+			expected: "SELECT * FROM \"users\" LIMIT 10 OFFSET 0",
+		},
+		{
+			// Single value slice on a column
+			url:      "data.sqlite;users;id,name[5]",
+			expected: "SELECT \"id\", \"name\" FROM \"users\" LIMIT 5 OFFSET 0",
+		},
+		{
+			// Negative slices count from the end and never reach SQL unresolved
+			url:      "data.sqlite;users[-10:]",
+			expected: "SELECT * FROM \"users\"",
+		},
 
 		// --- 5. Filtering (WHERE) ---
 		{
@@ -105,12 +148,47 @@ func TestConstructSQL(t *testing.T) {
 			// Path condition (custom banquet syntax if supported) AND query param
 			// Note: Current ParseBanquet implementation supports path conditions via parsePathConditions (x!=y)
 			url:      "data.sqlite;users;status!=active?where=age>18",
-			expected: "SELECT * FROM \"users\" WHERE age>18 AND status != 'active'",
+			expected: "SELECT * FROM \"users\" WHERE age>18 AND \"status\" != 'active'",
 		},
 		{
 			// Multiple path conditions
 			url:      "data.sqlite;users;status!=active,role!=admin",
-			expected: "SELECT * FROM \"users\" WHERE status != 'active' AND role != 'admin'",
+			expected: "SELECT * FROM \"users\" WHERE \"status\" != 'active' AND \"role\" != 'admin'",
+		},
+		{
+			// Comparison operators in path conditions
+			url:      "data.sqlite;users;status=active,age>=18",
+			expected: "SELECT * FROM \"users\" WHERE \"status\" = 'active' AND \"age\" >= 18",
+		},
+		{
+			// | separates OR alternatives inside one token
+			url:      "data.sqlite;users;status=active|status=pending",
+			expected: "SELECT * FROM \"users\" WHERE (\"status\" = 'active' OR \"status\" = 'pending')",
+		},
+		{
+			// Mixing , (AND) and | (OR)
+			url:      "data.sqlite;users;id,name,status=active|status=pending,age>21|role=admin",
+			expected: "SELECT \"id\", \"name\" FROM \"users\" WHERE (\"status\" = 'active' OR \"status\" = 'pending') AND (\"age\" > 21 OR \"role\" = 'admin')",
+		},
+		{
+			// Closed numeric range
+			url:      "data.sqlite;orders;total=50..500",
+			expected: "SELECT * FROM \"orders\" WHERE \"total\" BETWEEN 50 AND 500",
+		},
+		{
+			// Closed string range quotes the bounds
+			url:      "data.sqlite;orders;day=2024-01-01..2024-12-31",
+			expected: "SELECT * FROM \"orders\" WHERE \"day\" BETWEEN '2024-01-01' AND '2024-12-31'",
+		},
+		{
+			// Open upper range
+			url:      "data.sqlite;orders;total=50..",
+			expected: "SELECT * FROM \"orders\" WHERE \"total\" >= 50",
+		},
+		{
+			// Open lower range next to slice notation
+			url:      "data.sqlite;orders;id,total=..500[0:10]",
+			expected: "SELECT \"id\" FROM \"orders\" WHERE \"total\" <= 500 LIMIT 10 OFFSET 0",
 		},
 
 		// --- 6. Grouping and Having ---
@@ -122,7 +200,22 @@ func TestConstructSQL(t *testing.T) {
 		{
 			// Having clause
 			url:      "data.sqlite;users?groupby=country&having=count(*)>5",
-			expected: "SELECT * FROM \"users\" GROUP BY \"country\" HAVING count(*)>5",
+			expected: "SELECT * FROM \"users\" GROUP BY \"country\" HAVING count(*) > 5",
+		},
+		{
+			// Aggregate arguments are quoted, function names stay bare
+			url:      "data.sqlite;orders?groupby=country&having=sum(total)>100",
+			expected: "SELECT * FROM \"orders\" GROUP BY \"country\" HAVING sum(\"total\") > 100",
+		},
+		{
+			// A column on the right of a structured having is written @col
+			url:      "data.sqlite;orders?groupby=country&having=sum(total)>@budget",
+			expected: "SELECT * FROM \"orders\" GROUP BY \"country\" HAVING sum(\"total\") > budget",
+		},
+		{
+			// Repeated having params are ANDed
+			url:      "data.sqlite;orders?groupby=country&having=count(*)>5&having=sum(total)>100",
+			expected: "SELECT * FROM \"orders\" GROUP BY \"country\" HAVING count(*) > 5 AND sum(\"total\") > 100",
 		},
 
 		// --- 7. Complex Combinations ---
@@ -140,7 +233,12 @@ func TestConstructSQL(t *testing.T) {
 			// URL decoding in filters: "name!=O%27Reilly" decodes to "name!=O'Reilly"
 			// Then quoted to 'O''Reilly'
 			url:      "data.sqlite;users;name!=O%27Reilly",
-			expected: "SELECT * FROM \"users\" WHERE name != 'O''Reilly'",
+			expected: "SELECT * FROM \"users\" WHERE \"name\" != 'O''Reilly'",
+		},
+		{
+			// Condition columns are quoted, so spaces and reserved words survive
+			url:      "data.sqlite;resumes;id,first%20name!=Bob,order=5",
+			expected: "SELECT \"id\" FROM \"resumes\" WHERE \"first name\" != 'Bob' AND \"order\" = 5",
 		},
 
 		// --- 8. Heuristic Path Parsing (No Semicolons) ---
@@ -149,6 +247,21 @@ func TestConstructSQL(t *testing.T) {
 			url:      "file.csv/col1,col2",
 			expected: "SELECT \"col1\", \"col2\" FROM \"tb0\"",
 		},
+		{
+			// TSV is a flat file
+			url:      "data.tsv/a,b",
+			expected: "SELECT \"a\", \"b\" FROM \"tb0\"",
+		},
+		{
+			// Parquet is a flat file
+			url:      "data.parquet/a,b",
+			expected: "SELECT \"a\", \"b\" FROM \"tb0\"",
+		},
+		{
+			// Flat file 2-tier: second tier is columns, table stays implicit
+			url:      "file.csv;col1,col2",
+			expected: "SELECT \"col1\", \"col2\" FROM \"tb0\"",
+		},
 		{
 			// Heuristic: db.sqlite/table/col1 -> table explicit
 			url:      "db.sqlite/mytable/col1",
@@ -173,3 +286,875 @@ func TestConstructSQL(t *testing.T) {
 		})
 	}
 }
+
+func TestComposeErr(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr error
+	}{
+		{"data.sqlite;users;id", `SELECT "id" FROM "users"`, nil},
+		{"users.csv;id", `SELECT "id" FROM "tb0"`, nil},
+		{"data.sqlite", `SELECT * FROM "sqlite_master"`, nil},
+		// Columns without a table can't be inferred; Compose would emit FROM with no table
+		{"data.sqlite;;id,name", "", banquet.ErrNoTable},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		got, err := ComposeErr(bq)
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("ComposeErr(%q) = %q, %v, want %q, %v", tt.url, got, err, tt.want, tt.wantErr)
+		}
+	}
+	bq, _ := banquet.ParseBanquet("data.sqlite;;id,name")
+	if got, want := Compose(bq), `SELECT "id", "name" FROM `; got != want {
+		t.Errorf("Compose() = %q, want lenient %q", got, want)
+	}
+}
+
+func TestComposeStrict(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;id,name")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got, err := ComposeStrict(bq)
+	if err != nil {
+		t.Fatalf("ComposeStrict() error: %v", err)
+	}
+	if want := "SELECT \"id\", \"name\" FROM \"users\""; got != want {
+		t.Errorf("ComposeStrict() = %q, want %q", got, want)
+	}
+
+	injections := []string{
+		"data.sqlite;users;id,%22)%3B%20DROP%20TABLE%20x%3B--",
+		"data.sqlite;users%00;id",
+		"data.sqlite;users;id,name--x",
+		"data.sqlite;users;id?where=id=1%20UNION%20SELECT%20password%20FROM%20admins",
+		"data.sqlite;orders?having=count(*)>1;DELETE%20FROM%20orders",
+	}
+	for _, u := range injections {
+		bq, err := banquet.ParseBanquet(u)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", u, err)
+		}
+		var verr *banquet.ValidationError
+		if _, err := ComposeStrict(bq); !errors.As(err, &verr) {
+			t.Errorf("ComposeStrict(%q) error = %v, want *banquet.ValidationError", u, err)
+		}
+	}
+
+	// Ungrouped columns are rejected too
+	bq, _ = banquet.ParseBanquet("data.sqlite;users;id,country?groupby=country")
+	var verr *banquet.ValidationError
+	if _, err := ComposeStrict(bq); !errors.As(err, &verr) || verr.Value != "id" {
+		t.Errorf("ComposeStrict() error = %v, want the grouping error for id", err)
+	}
+}
+
+func TestComposeArgs(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;id,-age[10:30]?where=active=1")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got, args := ComposeArgs(bq)
+	want := "SELECT \"id\" FROM \"users\" WHERE active=1 ORDER BY \"age\" DESC LIMIT ? OFFSET ?"
+	if got != want {
+		t.Errorf("ComposeArgs() = %q, want %q", got, want)
+	}
+	if len(args) != 2 || args[0] != 20 || args[1] != 10 {
+		t.Errorf("ComposeArgs() args = %v, want [20 10]", args)
+	}
+
+	// Path condition values are bound too, numbers stay inline
+	bq, err = banquet.ParseBanquet("data.sqlite;users;id,status!=active,name~o'b,age>30[0:5]")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got, args = ComposeArgs(bq)
+	want = `SELECT "id" FROM "users" WHERE "status" != ? AND "name" LIKE ? AND "age" > 30 LIMIT ? OFFSET ?`
+	if got != want {
+		t.Errorf("ComposeArgs() = %q, want %q", got, want)
+	}
+	if want := []any{"active", "%o'b%", 5, 0}; !slices.Equal(args, want) {
+		t.Errorf("ComposeArgs() args = %v, want %v", args, want)
+	}
+}
+
+func BenchmarkCompose(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		url  string
+	}{
+		{"Simple", "data.sqlite;users"},
+		{"Complex", "data.sqlite;users;id,name,-age,status!=active[10:20]?where=age>18&groupby=country&having=count(*)>5"},
+	}
+	for _, bm := range benchmarks {
+		bq, err := banquet.ParseBanquet(bm.url)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Compose(bq)
+			}
+		})
+	}
+}
+
+func TestComposeIncludeSortInSelect(t *testing.T) {
+	tests := []struct {
+		url      string
+		opts     banquet.ParseOptions
+		expected string
+	}{
+		{
+			// Default drops the sort column from the select list
+			url:      "data.sqlite;users;id,+name",
+			expected: "SELECT \"id\" FROM \"users\" ORDER BY \"name\" ASC",
+		},
+		{
+			url:      "data.sqlite;users;id,+name",
+			opts:     banquet.ParseOptions{IncludeSortInSelect: true},
+			expected: "SELECT \"id\", \"name\" FROM \"users\" ORDER BY \"name\" ASC",
+		},
+		{
+			// Query param opt-in, sort columns keep their path position
+			url:      "data.sqlite;users;-age,id,email?select_sort=true",
+			expected: "SELECT \"age\", \"id\", \"email\" FROM \"users\" ORDER BY \"age\" DESC",
+		},
+		{
+			// Sort alone still selects everything
+			url:      "data.sqlite;users;+name",
+			opts:     banquet.ParseOptions{IncludeSortInSelect: true},
+			expected: "SELECT * FROM \"users\" ORDER BY \"name\" ASC",
+		},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquetWithOptions(tt.url, tt.opts)
+		if err != nil {
+			t.Fatalf("ParseBanquetWithOptions(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.expected {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestComposeFragmentComment(t *testing.T) {
+	tests := []struct {
+		url      string
+		opts     Options
+		expected string
+	}{
+		{"data.sqlite;users;id#fragment-top", Options{FragmentComment: true}, "SELECT \"id\" FROM \"users\" -- fragment-top"},
+		{"data.sqlite;users;id#fragment-top", Options{}, "SELECT \"id\" FROM \"users\""},
+		{"data.sqlite;users;id#a%0ADROP%20TABLE%20users", Options{FragmentComment: true}, "SELECT \"id\" FROM \"users\" -- a DROP TABLE users"},
+		{"data.sqlite;users;id", Options{FragmentComment: true}, "SELECT \"id\" FROM \"users\""},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := ComposeWithOptions(bq, tt.opts); got != tt.expected {
+			t.Errorf("ComposeWithOptions(%q, %+v) = %q, want %q", tt.url, tt.opts, got, tt.expected)
+		}
+	}
+}
+
+func TestComposeExclusions(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;!password,!ssn")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got := ComposeWithOptions(bq, Options{Columns: []string{"id", "name", "password", "ssn"}})
+	if want := `SELECT "id", "name" FROM "users"`; got != want {
+		t.Errorf("ComposeWithOptions() = %q, want %q", got, want)
+	}
+	// Without a column list the placeholder is emitted rather than a bare *
+	if got, want := Compose(bq), `SELECT * EXCEPT ("password", "ssn") FROM "users"`; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+	if _, err := ComposeStrict(bq); !errors.Is(err, banquet.ErrNoColumns) {
+		t.Errorf("ComposeStrict() error = %v, want ErrNoColumns", err)
+	}
+}
+
+func TestComposeStrictUnresolvedSlice(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users[-10:]")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if _, err := ComposeStrict(bq); !errors.Is(err, banquet.ErrUnresolvedSlice) {
+		t.Errorf("ComposeStrict() error = %v, want ErrUnresolvedSlice", err)
+	}
+	bq.ResolveFromEnd(100)
+	got, err := ComposeStrict(bq)
+	if err != nil {
+		t.Fatalf("ComposeStrict() after ResolveFromEnd error: %v", err)
+	}
+	if want := `SELECT * FROM "users" LIMIT 10 OFFSET 90`; got != want {
+		t.Errorf("ComposeStrict() = %q, want %q", got, want)
+	}
+}
+
+func TestComposeMaxLimit(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users?offset=5", `SELECT * FROM "users" LIMIT 100 OFFSET 5`},
+		{"data.sqlite;users[-100000:]", `SELECT * FROM "users" LIMIT 100`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquetWithOptions(tt.url, banquet.ParseOptions{MaxLimit: 100})
+		if err != nil {
+			t.Fatalf("ParseBanquetWithOptions(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestInferTableWithConfig(t *testing.T) {
+	tests := []struct {
+		url      string
+		cfg      TableConfig
+		expected string
+	}{
+		{"data/users.csv", TableConfig{}, "tb0"},
+		{"data/users.csv", TableConfig{UseFileStem: true}, "users"},
+		{"gs://bucket/exports/Orders.2024.tsv;id,total", TableConfig{UseFileStem: true}, "Orders.2024"},
+		{"data/users.csv", TableConfig{Name: "people", UseFileStem: true}, "people"},
+		{"data.sqlite", TableConfig{Name: "people"}, "sqlite_master"},
+		{"data.sqlite;;id,name", TableConfig{}, ""},
+		{"data.sqlite;users", TableConfig{Name: "people"}, "users"},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := InferTableWithConfig(bq, tt.cfg); got != tt.expected {
+			t.Errorf("InferTableWithConfig(%q, %+v) = %q, want %q", tt.url, tt.cfg, got, tt.expected)
+		}
+	}
+}
+
+func TestApplyInferredTable(t *testing.T) {
+	tests := []struct {
+		url    string
+		table  string
+		source banquet.TableSource
+	}{
+		{"data.sqlite;users", "users", banquet.TableExplicit},
+		{"data.sqlite/users", "users", banquet.TableHeuristic},
+		{"users.csv", "tb0", banquet.TableInferredDefault},
+		{"data.sqlite", "sqlite_master", banquet.TableInferredDefault},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := ApplyInferredTable(bq, TableConfig{}); got != tt.table || bq.Table != tt.table {
+			t.Errorf("ApplyInferredTable(%q) = %q (Table %q), want %q", tt.url, got, bq.Table, tt.table)
+		}
+		if bq.TableSource != tt.source {
+			t.Errorf("%s: TableSource = %v, want %v", tt.url, bq.TableSource, tt.source)
+		}
+	}
+}
+
+func TestComposeSchemaQualified(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;main.users;id")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := Compose(bq), `SELECT "id" FROM "main"."users"`; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+}
+
+func TestComposeQueryParams(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite?table=users&select=country&distinct=true")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := Compose(bq), `SELECT DISTINCT "country" FROM "users"`; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+}
+
+func TestComposeColumnComparison(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;orders;ship_date>@order_date", `SELECT * FROM "orders" WHERE "ship_date" > "order_date"`},
+		{"data.sqlite;orders;ship_date>order_date", `SELECT * FROM "orders" WHERE "ship_date" > 'order_date'`},
+		{"data.sqlite;orders;status=@", `SELECT * FROM "orders" WHERE "status" = '@'`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposePageTokens(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users;id,limit:10", `SELECT "id" FROM "users" LIMIT 10`},
+		{"data.sqlite;users;id,limit:10,offset:20", `SELECT "id" FROM "users" LIMIT 10 OFFSET 20`},
+		{"data.sqlite;users;limit:10?limit=5", `SELECT * FROM "users" LIMIT 5`},
+		{"data.sqlite;users;limit,offset", `SELECT "limit", "offset" FROM "users"`},
+		{"data.sqlite;users;limit:ten", `SELECT "limit:ten" FROM "users"`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestDialectNotEqual(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;status!=x")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	want := `"status" != 'x'`
+	if got := banquet.RendererFor(Dialect).Where(bq); got != want {
+		t.Errorf("Where = %q, want %q", got, want)
+	}
+	if got := Compose(bq); !strings.Contains(got, "WHERE "+want) {
+		t.Errorf("Compose() = %q, want WHERE %q", got, want)
+	}
+}
+
+func TestComposeWildcardWithExclusions(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;*,!password")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	got := ComposeWithOptions(bq, Options{Columns: []string{"id", "name", "password"}})
+	if want := `SELECT "id", "name" FROM "users"`; got != want {
+		t.Errorf("ComposeWithOptions() = %q, want %q", got, want)
+	}
+	if _, err := ComposeStrict(bq); !errors.Is(err, banquet.ErrNoColumns) {
+		t.Errorf("ComposeStrict() error = %v, want ErrNoColumns", err)
+	}
+
+	bq, _ = banquet.ParseBanquet("data.sqlite;users;name,*")
+	if got, want := Compose(bq), `SELECT "name", * FROM "users"`; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+}
+
+func TestComposeBareSlice(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;0:10")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := Compose(bq), `SELECT * FROM "users" LIMIT 10 OFFSET 0`; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+}
+
+func TestComposeBracketQuoting(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users;id,name,-age,status!=x", `SELECT [id], [name] FROM [users] WHERE [status] != 'x' ORDER BY [age] DESC`},
+		{"data.sqlite;main.users;odd]col", `SELECT [odd]]col] FROM [main].[users]`},
+		{"data.sqlite;users;*?groupby=city&having=count(id)>1", `SELECT * FROM [users] GROUP BY [city] HAVING count([id]) > 1`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := ComposeWithOptions(bq, Options{QuoteStyle: QuoteBracket}); got != tt.want {
+			t.Errorf("ComposeWithOptions(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+		// The default stays double quotes
+		if got := Compose(bq); strings.Contains(got, "[") {
+			t.Errorf("Compose(%q) = %q, want double quotes", tt.url, got)
+		}
+	}
+}
+
+func TestComposeTop(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;sales;-revenue?top=5", `SELECT * FROM "sales" ORDER BY "revenue" DESC LIMIT 5`},
+		// top wins over the slice, whose offset is dropped as well
+		{"data.sqlite;sales;-revenue[10:20]?top=5", `SELECT * FROM "sales" ORDER BY "revenue" DESC LIMIT 5`},
+		{"data.sqlite;sales;-revenue[-3:]?top=5", `SELECT * FROM "sales" ORDER BY "revenue" DESC LIMIT 5`},
+		{"data.sqlite;sales?top=5&limit=50", `SELECT * FROM "sales" LIMIT 5`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeScalarFunctions(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users;upper(name)", `SELECT upper("name") FROM "users"`},
+		{"data.sqlite;users;id,+date(created_at)", `SELECT "id" FROM "users" ORDER BY date("created_at") ASC`},
+		{"data.sqlite;users;substr(name,1,3),-length(name)", `SELECT substr("name", 1, 3) FROM "users" ORDER BY length("name") DESC`},
+		{"data.sqlite;users;coalesce(nick,name)?orderby=lower(name):desc", `SELECT coalesce("nick", "name") FROM "users" ORDER BY lower("name") DESC`},
+		// Unexpected arguments keep the whole token a quoted identifier
+		{"data.sqlite;users;upper(name||x)", `SELECT "upper(name||x)" FROM "users"`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeUnlimited(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users?limit=all", `SELECT * FROM "users"`},
+		{"data.sqlite;users?limit=-1", `SELECT * FROM "users"`},
+		// SQLite needs a LIMIT before OFFSET, so -1 stands in for no limit
+		{"data.sqlite;users?limit=all&offset=20", `SELECT * FROM "users" LIMIT -1 OFFSET 20`},
+		{"data.sqlite;users?offset=20", `SELECT * FROM "users" LIMIT -1 OFFSET 20`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+
+	bq, _ := banquet.ParseBanquet("data.sqlite;users?limit=all&offset=20")
+	query, args := ComposeArgs(bq)
+	if want := `SELECT * FROM "users" LIMIT ? OFFSET ?`; query != want || len(args) != 2 || args[0] != -1 || args[1] != 20 {
+		t.Errorf("ComposeArgs() = %q, %v, want %q, [-1 20]", query, args, want)
+	}
+}
+
+func TestComposeCommaInColumnName(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users;last%2C%20first,id", `SELECT "last, first", "id" FROM "users"`},
+		{`data.sqlite;users;last\, first,-last\, first`, `SELECT "last, first" FROM "users" ORDER BY "last, first" DESC`},
+		{"data.sqlite;users;last%2C%20first=Smith", `SELECT * FROM "users" WHERE "last, first" = 'Smith'`},
+		{`data.sqlite;users?select=last\,first,id`, `SELECT "last,first", "id" FROM "users"`},
+		// Commas inside a call still separate arguments
+		{"data.sqlite;users;substr(name,1,3)", `SELECT substr("name", 1, 3) FROM "users"`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+		// The escape survives Unparse
+		again, err := banquet.ParseBanquet(banquet.Unparse(bq))
+		if err != nil {
+			t.Fatalf("ParseBanquet(Unparse(%q)) error: %v", tt.url, err)
+		}
+		if got := Compose(again); got != tt.want {
+			t.Errorf("Compose(Unparse(%q)) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeOrderByPosition(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users;id,name,+2", `SELECT "id", "name" FROM "users" ORDER BY 2 ASC`},
+		{"data.sqlite;users;id,name,-1,+length(name)", `SELECT "id", "name" FROM "users" ORDER BY 1 DESC, length("name") ASC`},
+		{"data.sqlite;users;id,name?orderby=2:desc", `SELECT "id", "name" FROM "users" ORDER BY 2 DESC`},
+		// Positions can't be checked against *, and nulls ordering can't be emulated for them
+		{"data.sqlite;users;+3:nullsfirst", `SELECT * FROM "users" ORDER BY 3 ASC`},
+		// Past the select list the position is dropped and recorded in Errors
+		{"data.sqlite;users;id,name,+3,-id", `SELECT "id", "name" FROM "users" ORDER BY "id" DESC`},
+		// select_sort doesn't add a position to the select list
+		{"data.sqlite;users;id,name,+2,-age?select_sort=true", `SELECT "id", "name", "age" FROM "users" ORDER BY 2 ASC, "age" DESC`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeArithmetic(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;orders;total*1.1", `SELECT "total" * 1.1 FROM "orders"`},
+		{"data.sqlite;orders;id,price+tax,qty*price", `SELECT "id", "price" + "tax", "qty" * "price" FROM "orders"`},
+		{"data.sqlite;orders;total - 5,total*-1", `SELECT "total" - 5, "total" * -1 FROM "orders"`},
+		{"data.sqlite;orders;round(total,2)*2,round(total,2)-1", `SELECT round("total", 2) * 2, round("total", 2) - 1 FROM "orders"`},
+		{"data.sqlite;orders?select=total/qty", `SELECT "total" / "qty" FROM "orders"`},
+		// A leading sign is a sort prefix, even on an expression
+		{"data.sqlite;orders;id,-price*qty,+total", `SELECT "id" FROM "orders" ORDER BY "price" * "qty" DESC, "total" ASC`},
+		// A hyphen inside a name doesn't subtract, and malformed expressions stay names
+		{"data.sqlite;orders;first-name,address-2,utf-8,total-5,total*,a**b", `SELECT "first-name", "address-2", "utf-8", "total-5", "total*", "a**b" FROM "orders"`},
+		{"data.sqlite;orders;address-2*2,line-1 - line-2", `SELECT "address-2" * 2, "line-1" - "line-2" FROM "orders"`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeUnquoted(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users;id,name", `SELECT id, name FROM users`},
+		{"data.sqlite;main.users;id,-age,status!=x,upper(name)", `SELECT id, upper(name) FROM main.users WHERE status != 'x' ORDER BY age DESC`},
+		// Names that need quoting keep it
+		{"data.sqlite;users;first%20name,id%20FROM%20x", `SELECT "first name", "id FROM x" FROM users`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := ComposeWithOptions(bq, Options{QuoteStyle: QuoteNone}); got != tt.want {
+			t.Errorf("ComposeWithOptions(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeStructuredHaving(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;orders;country?groupby=country&having=count>5", `SELECT "country" FROM "orders" GROUP BY "country" HAVING count(*) > 5`},
+		{"data.sqlite;orders?groupby=country&having=count>5&having=sum(total)>=100.5", `SELECT * FROM "orders" GROUP BY "country" HAVING count(*) > 5 AND sum("total") >= 100.5`},
+		{"data.sqlite;orders?groupby=country&having=max(status)!=done", `SELECT * FROM "orders" GROUP BY "country" HAVING max("status") != 'done'`},
+		// Anything beyond a single comparison stays raw SQL
+		{"data.sqlite;orders?groupby=country&having=sum(a+b)>10", `SELECT * FROM "orders" GROUP BY "country" HAVING sum(a+b)>10`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeNullsOrdering(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users;id,+name:nullsfirst", `SELECT "id" FROM "users" ORDER BY CASE WHEN "name" IS NULL THEN 0 ELSE 1 END, "name" ASC`},
+		{"data.sqlite;users;id,-age:nullslast,+name", `SELECT "id" FROM "users" ORDER BY CASE WHEN "age" IS NULL THEN 1 ELSE 0 END, "age" DESC, "name" ASC`},
+		{"data.sqlite;users;id,+upper(name):NullsLast", `SELECT "id" FROM "users" ORDER BY CASE WHEN upper("name") IS NULL THEN 1 ELSE 0 END, upper("name") ASC`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeListTables(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;*tables", `SELECT "name" FROM "sqlite_master" WHERE "type" = 'table'`},
+		{"data.sqlite;*tables;name,sql,+name,name!=sqlite_sequence", `SELECT "name", "sql" FROM "sqlite_master" WHERE "type" = 'table' AND "name" != 'sqlite_sequence' ORDER BY "name" ASC`},
+		{"data.sqlite;*tables;*", `SELECT * FROM "sqlite_master" WHERE "type" = 'table'`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeRawSQL(t *testing.T) {
+	bq, err := banquet.ParseBanquet("data.sqlite;users;id?rawsql=SELECT%20count(*)%20FROM%20users%20WHERE%20a+b>1&limit=5")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	raw := "SELECT count(*) FROM users WHERE a+b>1"
+	if bq.RawSQL != raw {
+		t.Fatalf("RawSQL = %q, want %q", bq.RawSQL, raw)
+	}
+
+	// Allowed: the raw SQL replaces every other clause
+	if got := ComposeWithOptions(bq, Options{AllowRawSQL: true}); got != raw {
+		t.Errorf("ComposeWithOptions(AllowRawSQL) = %q, want %q", got, raw)
+	}
+	if got, err := ComposeStrictWithOptions(bq, Options{AllowRawSQL: true}); err != nil || got != raw {
+		t.Errorf("ComposeStrictWithOptions(AllowRawSQL) = %q, %v, want %q", got, err, raw)
+	}
+
+	// Disallowed by default: ignored by the lenient composers, an error from the strict ones
+	if got, want := Compose(bq), `SELECT "id" FROM "users" LIMIT 5`; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+	if _, err := ComposeStrict(bq); !errors.Is(err, banquet.ErrRawSQL) {
+		t.Errorf("ComposeStrict() error = %v, want banquet.ErrRawSQL", err)
+	}
+}
+
+func TestComposePreservesCasing(t *testing.T) {
+	bq, err := banquet.ParseBanquet("Users.CSV;ID,Name")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got, want := Compose(bq), `SELECT "ID", "Name" FROM "tb0"`; got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+	if got := InferTableWithConfig(bq, TableConfig{UseFileStem: true}); got != "Users" {
+		t.Errorf("InferTableWithConfig(UseFileStem) = %q, want Users", got)
+	}
+
+	bq, err = banquet.ParseBanquet("DATA.SQLITE")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	if got := InferTable(bq); got != "sqlite_master" {
+		t.Errorf("InferTable(DATA.SQLITE) = %q, want sqlite_master", got)
+	}
+}
+
+func TestComposeTupleIn(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;orders;(region,product)=[(west,widget),(east,gadget)]",
+			`SELECT * FROM "orders" WHERE ("region","product") IN (('west','widget'),('east','gadget'))`},
+		{"data.sqlite;orders;id,(region,year)=[ (west,2024), (east,2025) ],-id",
+			`SELECT "id" FROM "orders" WHERE ("region","year") IN (('west',2024),('east',2025)) ORDER BY "id" DESC`},
+		{"data.sqlite;orders;(region,year)=[(west,2024)][0:10]",
+			`SELECT * FROM "orders" WHERE ("region","year") IN (('west',2024)) LIMIT 10 OFFSET 0`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeKeywordCase(t *testing.T) {
+	tests := []struct {
+		url   string
+		opts  Options
+		upper string
+		lower string
+	}{
+		{
+			url:   "data.sqlite;users;id,+name:nullsfirst,status!=active,age=18..65[10:20]?distinct=true",
+			upper: `SELECT DISTINCT "id" FROM "users" WHERE "status" != 'active' AND "age" BETWEEN 18 AND 65 ORDER BY CASE WHEN "name" IS NULL THEN 0 ELSE 1 END, "name" ASC LIMIT 10 OFFSET 10`,
+			lower: `select distinct "id" from "users" where "status" != 'active' and "age" between 18 and 65 order by case when "name" is null then 0 else 1 end, "name" asc limit 10 offset 10`,
+		},
+		{
+			// Identifiers, literals and the fragment comment keep their case
+			url:   "data.sqlite;ORDER;SELECT,kind=AND,region=WEST|region=EAST#FROM Report",
+			opts:  Options{FragmentComment: true},
+			upper: `SELECT "SELECT" FROM "ORDER" WHERE "kind" = 'AND' AND ("region" = 'WEST' OR "region" = 'EAST') -- FROM Report`,
+			lower: `select "SELECT" from "ORDER" where "kind" = 'AND' and ("region" = 'WEST' or "region" = 'EAST') -- FROM Report`,
+		},
+		{
+			url:   "data.sqlite;Users;Status,-Age",
+			opts:  Options{QuoteStyle: QuoteNone},
+			upper: `SELECT Status FROM Users ORDER BY Age DESC`,
+			lower: `select Status from Users order by Age desc`,
+		},
+		{
+			url:   "data.sqlite;users;id",
+			opts:  Options{QuoteStyle: QuoteBracket},
+			upper: `SELECT [id] FROM [users]`,
+			lower: `select [id] from [users]`,
+		},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := ComposeWithOptions(bq, tt.opts); got != tt.upper {
+			t.Errorf("ComposeWithOptions(%q) = %q, want %q", tt.url, got, tt.upper)
+		}
+		tt.opts.KeywordCase = KeywordLower
+		if got := ComposeWithOptions(bq, tt.opts); got != tt.lower {
+			t.Errorf("ComposeWithOptions(%q, KeywordLower) = %q, want %q", tt.url, got, tt.lower)
+		}
+	}
+}
+
+func TestComposeSubquery(t *testing.T) {
+	inner, err := banquet.ParseBanquet("data.sqlite;orders;country,region?groupby=country&having=count>5#busy")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	outer := banquet.Subquery(inner, "busy")
+	outer.Conditions = []banquet.Condition{{Column: "region", Operator: banquet.OpNe, Value: "west"}}
+	outer.Sorts = []banquet.OrderTerm{{Column: "country", Direction: "ASC"}}
+	outer.Limit = "10"
+
+	want := `SELECT * FROM (SELECT "country", "region" FROM "orders" GROUP BY "country" HAVING count(*) > 5) AS "busy" WHERE "region" != 'west' ORDER BY "country" ASC LIMIT 10`
+	if got := Compose(outer); got != want {
+		t.Errorf("Compose() = %q, want %q", got, want)
+	}
+	// The inner fragment comment would swallow the outer clauses, so it is left out
+	if got := ComposeWithOptions(outer, Options{FragmentComment: true}); got != want {
+		t.Errorf("ComposeWithOptions(FragmentComment) = %q, want %q", got, want)
+	}
+	// Subqueries nest
+	outer = banquet.Subquery(banquet.Subquery(inner, "busy"), "outer")
+	want = `SELECT * FROM (SELECT * FROM (SELECT "country", "region" FROM "orders" GROUP BY "country" HAVING count(*) > 5) AS "busy") AS "outer"`
+	if got := Compose(outer); got != want {
+		t.Errorf("Compose(nested) = %q, want %q", got, want)
+	}
+	// The subquery is validated too
+	bad := banquet.Subquery(&banquet.Banquet{Table: "orders", Where: "1=1; DROP TABLE orders"}, "o")
+	if _, err := ComposeStrict(bad); err == nil {
+		t.Errorf("ComposeStrict(bad subquery) = nil error, want a validation error")
+	}
+}
+
+func TestComposeContains(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite;users;id,name~smith", `SELECT "id" FROM "users" WHERE "name" LIKE '%smith%'`},
+		{"data.sqlite;users;name~o'brien|email~smith", `SELECT * FROM "users" WHERE ("name" LIKE '%o''brien%' OR "email" LIKE '%smith%')`},
+		// Wildcards in the value match literally
+		{"data.sqlite;sales;note~50%25_off", `SELECT * FROM "sales" WHERE "note" LIKE '%50!%!_off%' ESCAPE '!'`},
+		// Numbers and @ are text here
+		{"data.sqlite;users;phone~555,handle~@dev", `SELECT * FROM "users" WHERE "phone" LIKE '%555%' AND "handle" LIKE '%@dev%'`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got := Compose(bq); got != tt.want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestComposeColumnTypes(t *testing.T) {
+	types := map[string]banquet.ColumnType{
+		"zip":    banquet.TypeText,
+		"code":   banquet.TypeNumeric,
+		"active": banquet.TypeBoolean,
+		"region": banquet.TypeText,
+	}
+	tests := []struct {
+		url     string
+		guessed string
+		typed   string
+	}{
+		{"data.sqlite;users;zip=12345", `"zip" = 12345`, `"zip" = '12345'`},
+		{"data.sqlite;users;code=007", `"code" = '007'`, `"code" = 007`},
+		{"data.sqlite;users;active=true", `"active" = 'true'`, `"active" = TRUE`},
+		{"data.sqlite;users;active!=0", `"active" != 0`, `"active" != 0`},
+		// A value that doesn't fit the type is still quoted
+		{"data.sqlite;users;code=abc,active=yes", `"code" = 'abc' AND "active" = 'yes'`, `"code" = 'abc' AND "active" = 'yes'`},
+		{"data.sqlite;users;zip=10000..19999|age=30", `("zip" BETWEEN 10000 AND 19999 OR "age" = 30)`, `("zip" BETWEEN '10000' AND '19999' OR "age" = 30)`},
+		{"data.sqlite;users;(region,code)=[(1,007)]", `("region","code") IN ((1,'007'))`, `("region","code") IN (('1',007))`},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) error: %v", tt.url, err)
+		}
+		if got, want := Compose(bq), `SELECT * FROM "users" WHERE `+tt.guessed; got != want {
+			t.Errorf("Compose(%q) = %q, want %q", tt.url, got, want)
+		}
+		if got, want := ComposeWithOptions(bq, Options{Types: types}), `SELECT * FROM "users" WHERE `+tt.typed; got != want {
+			t.Errorf("ComposeWithOptions(%q, Types) = %q, want %q", tt.url, got, want)
+		}
+	}
+}
+
+func TestComposeSubqueryOptions(t *testing.T) {
+	// The caller's options apply inside a subquery as at the top level
+	inner, err := banquet.ParseBanquet("data.sqlite;users;!password,zip=12345")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	opts := Options{Types: map[string]banquet.ColumnType{"zip": banquet.TypeText}, Columns: []string{"id", "password", "zip"}}
+	if got, want := ComposeWithOptions(banquet.Subquery(inner, "u"), opts), `SELECT * FROM (SELECT "id", "zip" FROM "users" WHERE "zip" = '12345') AS "u"`; got != want {
+		t.Errorf("ComposeWithOptions(subquery) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,29 @@
+package banquet
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// maxStreamLine is the longest URL ParseStream accepts.
+const maxStreamLine = 1 << 20
+
+// ParseStream parses r one URL per line, calling fn with each result as it goes, so large
+// URL logs are never held in memory. Blank lines are skipped. A line that fails to parse is
+// passed to fn with its error and the stream continues. A read error, including a line over
+// 1 MiB, ends the stream with a final fn(nil, err).
+func ParseStream(r io.Reader, fn func(*Banquet, error)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxStreamLine)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fn(ParseBanquet(line))
+	}
+	if err := scanner.Err(); err != nil {
+		fn(nil, err)
+	}
+}
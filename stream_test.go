@@ -0,0 +1,43 @@
+package banquet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseStream(t *testing.T) {
+	input := "data.sqlite;users;id,name\n\n   \ndata.csv;amount?limit=5\r\nhttp://[::1\nlast.sqlite;orders"
+	var tables []string
+	var errs int
+	ParseStream(strings.NewReader(input), func(b *Banquet, err error) {
+		if err != nil {
+			errs++
+			return
+		}
+		tables = append(tables, b.DataSetPath+";"+b.Table)
+	})
+	if want := "data.sqlite;users data.csv; last.sqlite;orders"; strings.Join(tables, " ") != want {
+		t.Errorf("parsed %q, want %q", tables, want)
+	}
+	if errs != 1 {
+		t.Errorf("got %d errors, want 1 for the malformed line", errs)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("disk on fire") }
+
+func TestParseStreamReadError(t *testing.T) {
+	var got error
+	ParseStream(failingReader{}, func(b *Banquet, err error) {
+		if b != nil {
+			t.Errorf("unexpected Banquet %v", b)
+		}
+		got = err
+	})
+	if got == nil || got.Error() != "disk on fire" {
+		t.Errorf("error = %v, want disk on fire", got)
+	}
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/darianmavgo/banquet"
+	"github.com/darianmavgo/banquet/sqlite"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSqliteQuery(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open sqlite db: %v", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT, age INTEGER)`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	_, err = db.Exec(`INSERT INTO people (name, age) VALUES ('Ann', 34), ('Bob', 17), ('Cid', 52), ('Dee', 41)`)
+	if err != nil {
+		t.Fatalf("Failed to insert rows: %v", err)
+	}
+
+	b, err := banquet.ParseBanquet("people.sqlite;people;name,-age?where=age>18&limit=2")
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %v", err)
+	}
+
+	rows, err := sqlite.Query(db, b)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var results []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		results = append(results, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Rows failed: %v", err)
+	}
+
+	expected := []string{"Cid", "Dee"}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Errorf("Expected result[%d] %q, got %q", i, expected[i], results[i])
+		}
+	}
+}
+
+func TestSqliteSourceExecute(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open sqlite db: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE people (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO people (name) VALUES ('Ann'), ('Bob')`); err != nil {
+		t.Fatalf("Failed to seed table: %v", err)
+	}
+
+	banquet.RegisterSource("sqlite", sqlite.Source{DB: db})
+	rows, err := banquet.Execute(context.Background(), "people.sqlite;people;id,name?where=name='Bob'")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(rows.Columns) != 2 || len(rows.Values) != 1 || rows.Values[0][1] != "Bob" {
+		t.Errorf("Execute = %+v, want one row for Bob", rows)
+	}
+}
+
+func TestSqliteListTables(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open sqlite db: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE people (id INTEGER); CREATE TABLE orders (id INTEGER); CREATE VIEW adults AS SELECT * FROM people`); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+
+	b, err := banquet.ParseBanquet("people.sqlite;*tables;name,+name")
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %v", err)
+	}
+	rows, err := sqlite.Query(db, b)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Rows failed: %v", err)
+	}
+	// The view is not a table
+	if len(names) != 2 || names[0] != "orders" || names[1] != "people" {
+		t.Errorf("tables = %v, want [orders people]", names)
+	}
+}
@@ -27,7 +27,7 @@ func TestNotNull(t *testing.T) {
 	// 3. Verify Where clause is constructed correctly
 	// The user expects "translate != to whatever the correct syntax is for sqlite"
 	// And since the value is a string, it should be quoted.
-	expectedWhere := "academic_resume_cv != 'Undergraduate Studies'"
+	expectedWhere := `"academic_resume_cv" != 'Undergraduate Studies'`
 	if !strings.Contains(b.Where, expectedWhere) {
 		t.Errorf("Expected Where clause to contain %q, got %q", expectedWhere, b.Where)
 	}
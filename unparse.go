@@ -0,0 +1,257 @@
+package banquet
+
+import (
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// slicePattern matches bracketed slice notation such as [10:20], [:5], [3:] or [10].
+var slicePattern = regexp.MustCompile(`\[\s*-?\d*\s*:\s*-?\d*\s*\]|\[\s*\d+\s*\]`)
+
+// Unparse renders b back into a canonical Banquet URL.
+// Tiers are written explicitly with semicolons (dataset;table;columns), slice notation is
+// replaced by limit and offset query params, and the query string is encoded in sorted key order.
+// Parsing the result yields the same clauses as b.
+func Unparse(b *Banquet) string {
+	path := canonicalPath(b)
+	if b.FromEnd {
+		// Negative slices have no LIMIT/OFFSET equivalent until resolved, so keep the notation
+		path += "[" + b.sliceStart + ":" + b.sliceEnd + "]"
+	}
+	if b.URL == nil || b.Scheme == "" {
+		// CleanUrl may have prefixed ./ to protect a colon; url.URL re-adds it when still needed
+		if b.URL == nil || strings.HasPrefix(b.rawurl, "./") && ProtectColonSegment(b.rawurl[2:]) != b.rawurl[2:] {
+			path = strings.TrimPrefix(path, "./")
+		}
+		if b.URL == nil || b.Host == "" {
+			// ParseBanquet trims a leading slash from scheme-less URLs, so don't emit one
+			path = TrimLeadingSlash(path)
+		}
+	}
+	u := url.URL{Path: path}
+	if b.URL != nil {
+		u.Scheme = b.Scheme
+		u.User = b.User
+		u.Host = b.Host
+		u.Fragment = b.Fragment
+	}
+
+	var query url.Values
+	var rawQuery string
+	if b.URL != nil {
+		query, rawQuery = b.Query(), b.RawQuery
+	} else {
+		query = url.Values{}
+	}
+	if b.FromEnd {
+		// A limit next to the slice would stop it counting from the end; a MaxLimit cap is reapplied on parse
+		query.Del("limit")
+		query.Del("offset")
+	} else {
+		setOrDelete(query, "limit", b.Limit)
+		setOrDelete(query, "offset", b.Offset)
+	}
+	u.RawQuery = encodeQuery(query, rawQuery)
+
+	return u.String()
+}
+
+// Canonicalize returns b rewritten in the explicit dataset;table;columns form however it was
+// written: a heuristic slash path, the table and select query params, a path group-by and a
+// slice all end up as tiers and query params, with the column tier rebuilt by
+// ColumnPathCanonical. Unparse of the result is the canonical URL, so downstream systems see
+// one shape per query. b is not modified.
+func (b *Banquet) Canonicalize() *Banquet {
+	c := b.Clone()
+	c.ColumnPath = b.ColumnPathCanonical()
+	if c.URL != nil {
+		query := c.Query()
+		query.Del("select")
+		query.Del("table")
+		if c.GroupBy != "" && query.Get("groupby") == "" {
+			query.Set("groupby", c.GroupBy)
+		}
+		c.RawQuery = encodeQuery(query, c.RawQuery)
+	}
+	canonical, err := ParseBanquet(Unparse(c))
+	if err != nil {
+		// Unparse output parses back by design; keep the rewritten copy if it somehow doesn't
+		return c
+	}
+	return canonical
+}
+
+// rawParams are the query params ParseBanquet decodes %-escapes only, leaving + as +.
+var rawParams = map[string]bool{"where": true, "having": true, "rawsql": true}
+
+// encodeQuery encodes query like url.Values.Encode, in sorted key order, but writes spaces
+// as %20. The values of rawParams are taken from rawQuery instead: query is form decoded, so
+// the + of where=price+tax>100 would already be a space there.
+func encodeQuery(query url.Values, rawQuery string) string {
+	keys := slices.Sorted(maps.Keys(query))
+	var buf strings.Builder
+	for _, k := range keys {
+		values := query[k]
+		escape := func(v string) string { return strings.ReplaceAll(url.QueryEscape(v), "+", "%20") }
+		if rawParams[k] {
+			values = rawQueryValues(rawQuery, k)
+			// PathEscape keeps + literal; & would end the param
+			escape = func(v string) string { return strings.ReplaceAll(url.PathEscape(v), "&", "%26") }
+		}
+		for _, v := range values {
+			if buf.Len() > 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(url.QueryEscape(k) + "=" + escape(v))
+		}
+	}
+	return buf.String()
+}
+
+// canonicalPath joins the dataset, table and column tiers with semicolons.
+func canonicalPath(b *Banquet) string {
+	// Without a table or extension the slice stays on the dataset tier
+	dataset := escapeTier(stripSlices(b.DataSetPath))
+	columns := stripSlices(b.ColumnPath)
+	table := b.QualifiedTable()
+	// A heuristic table is also the first segment of the column path; drop the repetition.
+	// Explicit tiers already keep the two apart.
+	if table != "" && (b.URL == nil || !hasTiers(b.Path)) {
+		columns = strings.TrimPrefix(columns, table+"/")
+		// A lone column named like the table selects *, see ParseBanquet
+		if columns == table {
+			columns = ""
+		}
+	}
+	// Literal semicolons in any tier are escaped as \;
+	table, columns = escapeTier(table), escapeTier(columns)
+	// The output format suffix goes back on the last tier. Without one, a last tier that merely
+	// ends in .csv or .json is kept from reading as a format: a column list by a trailing comma,
+	// a table by an empty column tier. The trailing comma also keeps a last column such as 0:10,
+	// left over when a second N:M token was the slice, from reading as a bare slice.
+	keepColumnTier := false
+	switch {
+	case b.OutputFormat != "" && columns != "":
+		columns += "." + b.OutputFormat
+	case b.OutputFormat != "" && table != "":
+		table += "." + b.OutputFormat
+	case columns != "":
+		if _, format := cutOutputFormat(columns); format != "" || bareSlice.MatchString(columns) {
+			columns += ","
+		}
+	case table != "":
+		_, format := cutOutputFormat(table)
+		keepColumnTier = format != ""
+	}
+
+	switch {
+	case table == "" && columns == "" && (!survivesHeuristic(dataset) || b.URL != nil && b.Query().Get("table") != ""):
+		// Keep the tier separator so neither the heuristic nor a table param picks a table
+		return dataset + ";"
+	case table == "" && columns == "":
+		return dataset
+	case table == "":
+		return dataset + ";;" + columns
+	case columns == "" && !isFlatFile(dataset) && !keepColumnTier:
+		return dataset + ";" + table
+	default:
+		// Flat files need the third tier so the table isn't read back as a column list
+		return dataset + ";" + table + ";" + columns
+	}
+}
+
+// stripSlices removes slice notation from s, repeating until none is left since removing
+// a nested slice such as [0[0]] can expose another.
+func stripSlices(s string) string {
+	for {
+		stripped := slicePattern.ReplaceAllString(s, "")
+		if stripped == s {
+			return s
+		}
+		s = stripped
+	}
+}
+
+// survivesHeuristic reports whether a semicolon-less dataset parses back to itself.
+func survivesHeuristic(dataset string) bool {
+	parsed, _, columns := parseDataSetColumnPath(dataset)
+	return parsed == unescapeTier(dataset) && columns == ""
+}
+
+// ColumnPathCanonical regenerates the column path from the structured fields of b:
+// selected columns, !exclusions, +/- sorts and conditions, in that order and comma separated,
+// e.g. id,name,+age,status!=active. An inferred * is omitted, and sorts taken from the
+// orderby query param are left to the query string.
+func (b *Banquet) ColumnPathCanonical() string {
+	var tokens []string
+	if b.SelectAll || len(b.Select) > 0 && !(len(b.Select) == 1 && b.Select[0] == "*") {
+		for _, col := range b.Select {
+			tokens = append(tokens, escapeComma(col))
+		}
+	}
+	for _, col := range b.Exclude {
+		tokens = append(tokens, "!"+escapeComma(col))
+	}
+	if b.URL == nil || b.Query().Get("orderby") == "" {
+		for _, sort := range b.Sorts {
+			nulls := ""
+			if sort.Nulls != "" {
+				nulls = ":nulls" + strings.ToLower(sort.Nulls)
+			}
+			switch sort.Direction {
+			case "ASC":
+				tokens = append(tokens, ASC+escapeComma(sort.Column)+nulls)
+			case "DESC":
+				tokens = append(tokens, DESC+escapeComma(sort.Column)+nulls)
+			}
+		}
+	}
+	for _, cond := range b.Conditions {
+		tokens = append(tokens, cond.pathToken())
+	}
+	return strings.Join(tokens, ",")
+}
+
+func setOrDelete(v url.Values, key, value string) {
+	if value == "" {
+		v.Del(key)
+		return
+	}
+	v.Set(key, value)
+}
+
+// NextPage returns the canonical URL of the page after b, advancing Offset by Limit.
+// Without a numeric Limit the canonical URL of b is returned unchanged.
+func NextPage(b *Banquet) string {
+	return page(b, 1)
+}
+
+// PrevPage returns the canonical URL of the page before b, retreating Offset by Limit
+// and clamping at 0. Without a numeric Limit the canonical URL of b is returned unchanged.
+func PrevPage(b *Banquet) string {
+	return page(b, -1)
+}
+
+func page(b *Banquet, direction int) string {
+	limit, err := strconv.Atoi(b.Limit)
+	if err != nil || limit <= 0 {
+		return Unparse(b)
+	}
+	offset := 0
+	if b.Offset != "" {
+		if offset, err = strconv.Atoi(b.Offset); err != nil {
+			return Unparse(b)
+		}
+	}
+	offset += direction * limit
+	if offset < 0 {
+		offset = 0
+	}
+	c := b.Clone()
+	c.Offset = strconv.Itoa(offset)
+	return Unparse(c)
+}
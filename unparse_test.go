@@ -0,0 +1,257 @@
+package banquet
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnparse(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite", "data.sqlite"},
+		{"data.sqlite;users", "data.sqlite;users"},
+		{"data.sqlite;users;id,name,-age", "data.sqlite;users;id,name,-age"},
+		{"db.sqlite/mytable/col1", "db.sqlite;mytable;col1"},
+		{"file.csv/col1,col2", "file.csv;;col1,col2"},
+		{"users.csv/name", "users.csv;name;"},
+		{"data.sqlite;users[10:20]", "data.sqlite;users?limit=10&offset=10"},
+		{"data.sqlite;users;id[5]", "data.sqlite;users;id?limit=5&offset=0"},
+		{"data.sqlite;users[-10:]", "./data.sqlite;users%5B-10:%5D"},
+		{"gs://matrix@bucket/data.sqlite;users;id?where=age>18", "gs://matrix@bucket/data.sqlite;users;id?where=age%3E18"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		got := Unparse(b)
+		if got != tt.want {
+			t.Errorf("Unparse(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+
+		// The canonical form parses back to the same clauses
+		again, err := ParseBanquet(got)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", got, err)
+		}
+		if again.Table != b.Table || again.Where != b.Where || again.Limit != b.Limit || again.Offset != b.Offset ||
+			!equalStrings(again.Select, b.Select) || again.OrderBy != b.OrderBy || again.FromEnd != b.FromEnd {
+			t.Errorf("Reparse of %q diverged from %q", got, tt.url)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestColumnPathCanonical(t *testing.T) {
+	tests := []struct{ url, want string }{
+		{"data.sqlite;users", ""},
+		{"data.sqlite;users;*", "*"},
+		{"data.sqlite;users;id,name,+age", "id,name,+age"},
+		{"data.sqlite;users;id,-age,status!=active", "id,-age,status!=active"},
+		{"data.sqlite;users;!password,!ssn", "!password,!ssn"},
+		{"data.sqlite;users;id,total=50..500,kind=a|kind=b", "id,total=50..500,kind=a|kind=b"},
+		{"data.sqlite;orders;ship_date>@order_date", "ship_date>@order_date"},
+		{"data.sqlite;users;city=New%20York", "city=New%20York"},
+		{"data.sqlite;users;id[10:20]", "id"},
+		{"data.sqlite;users;id?orderby=name:desc", "id"},
+		{"data.sqlite;orders;(region,year)=[(west,2024),(east%20north,2025)]", "(region,year)=[(west,2024),(east%20north,2025)]"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		got := b.ColumnPathCanonical()
+		if got != tt.want {
+			t.Errorf("%s: ColumnPathCanonical() = %q, want %q", tt.url, got, tt.want)
+		}
+
+		// The regenerated path parses back to the same clauses
+		r, err := ParseBanquet("data.sqlite;users;" + got)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", got, err)
+		}
+		if r.ColumnPathCanonical() != got || r.Where != b.Where {
+			t.Errorf("%s: regenerated %q parses to %q with Where %q, want Where %q", tt.url, got, r.ColumnPathCanonical(), r.Where, b.Where)
+		}
+	}
+}
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		url  string
+		next string
+		prev string
+	}{
+		{"data.sqlite;users?limit=10&offset=20", "data.sqlite;users?limit=10&offset=30", "data.sqlite;users?limit=10&offset=10"},
+		{"data.sqlite;users[0:10]", "data.sqlite;users?limit=10&offset=10", "data.sqlite;users?limit=10&offset=0"},
+		{"data.sqlite;users?limit=10&offset=5", "data.sqlite;users?limit=10&offset=15", "data.sqlite;users?limit=10&offset=0"},
+		{"data.sqlite;users?limit=10", "data.sqlite;users?limit=10&offset=10", "data.sqlite;users?limit=10&offset=0"},
+		// No limit: unchanged
+		{"data.sqlite;users?offset=5", "data.sqlite;users?offset=5", "data.sqlite;users?offset=5"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		offset := b.Offset
+		if got := NextPage(b); got != tt.next {
+			t.Errorf("NextPage(%q) = %q, want %q", tt.url, got, tt.next)
+		}
+		if got := PrevPage(b); got != tt.prev {
+			t.Errorf("PrevPage(%q) = %q, want %q", tt.url, got, tt.prev)
+		}
+		if b.Offset != offset {
+			t.Errorf("Source Offset mutated from %q to %q", offset, b.Offset)
+		}
+	}
+}
+
+func TestUnparseKeepsPlus(t *testing.T) {
+	for _, u := range []string{
+		"data.sqlite;orders?where=price+tax>100&limit=10",
+		"data.sqlite;orders?having=sum(price+tax)>100%20AND%20count(*)>1&groupby=region&limit=10",
+		"data.sqlite;orders?where=note='a+b%26c'&limit=10",
+	} {
+		b, err := ParseBanquet(u)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", u, err)
+		}
+		for name, got := range map[string]string{
+			"Unparse":      Unparse(b),
+			"NextPage":     NextPage(b),
+			"Canonicalize": Unparse(b.Canonicalize()),
+		} {
+			r, err := ParseBanquet(got)
+			if err != nil {
+				t.Fatalf("ParseBanquet(%s(%q)) = %q failed: %v", name, u, got, err)
+			}
+			if r.Where != b.Where || r.Having != b.Having {
+				t.Errorf("%s(%q) = %q reads back where %q having %q, want %q and %q", name, u, got, r.Where, r.Having, b.Where, b.Having)
+			}
+		}
+	}
+}
+
+// FuzzRoundTrip checks that Unparse is a fixed point after the first normalization:
+// re-parsing an unparsed URL and unparsing it again yields the same URL and clauses.
+func FuzzRoundTrip(f *testing.F) {
+	for _, seed := range []string{
+		"http://localhost:8080/https://bucket.appspot.com:8080/v1/{banquet}/path:with;@+,$/[^]|\\< >~%25/column1,column2/+const?orderid=-1&tag=prime+val&filter={status:active}&search=~alt#fragment-top",
+		"/http:/darianhickman.com:8080/some/local/path/file.csv;col1,col2,col3",
+		"http://localhost:8080/gs:/my-bucket/database.sqlite;customers;id,name,+age?where=age>18&limit=50",
+		"gs://bucket.appspot.com:8080/some/file/path.csv/column1,column2,+column3?where=age>20&limit=10&offset=5&groupby=department&having=count>1",
+		"gs://matrix@bucket.appspot.com:8080/some/file/path.csv/column1,column2/+column3?orderid=1",
+		"users.csv;name",
+		"data.sqlite;users",
+		"data/sales.csv/amount",
+		"data.sqlite/tableorcolumn",
+		"data.sqlite;users[-10:]",
+		"data.sqlite;users;id,name[10:20]",
+		"data.sqlite;users;id,-age,status!=active,total=50..500,kind=a|kind=b",
+		"data.sqlite;public.users;!password?distinct=true",
+		"data.sqlite?table=users&select=id,name",
+		"data.sqlite;orders?having=count(*)>5&having=sum(total)>100%20OR%20avg(total)>10",
+		"data.sqlite;orders?where=price+tax>100%20AND%20qty>1",
+		"data.sqlite;users;id,+name:nullsfirst?orderby=age:desc:nullslast",
+		"data.sqlite;users;id,name.csv",
+		"data.sqlite/users.json",
+		"data.sqlite;orders;id,(region,product)=[(west,widget),(east,gadget)]",
+		"data.sqlite;users;last%2C%20first,-id,last\\,name=x",
+		"data.sqlite;users;name~smith|note~50%25_off",
+		"0;;0:0,0:0",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		b1, err := ParseBanquet(raw)
+		if err != nil || addressesNothing(b1) || b1.Scheme == "" && (b1.Host != "" || strings.HasPrefix(b1.Path, "/")) {
+			// Unparseable, addresses nothing (a bare scheme) or scheme-less with an authority or
+			// extra leading slashes (//host, ///path), which CleanUrl reads back differently
+			return
+		}
+		if strings.HasSuffix(b1.DataSetPath, `\`) || strings.HasSuffix(b1.QualifiedTable(), `\`) {
+			// A tier ending in a backslash would run into the next separator as \;
+			return
+		}
+		u1 := Unparse(b1)
+		b2, err := ParseBanquet(u1)
+		if err != nil {
+			t.Fatalf("ParseBanquet(Unparse(%q)) = %q failed: %v", raw, u1, err)
+		}
+		u2 := Unparse(b2)
+		b3, err := ParseBanquet(u2)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", u2, err)
+		}
+		if u1 != u2 {
+			t.Errorf("Unparse not stable for %q: %q then %q", raw, u1, u2)
+		}
+		if !sameClauses(b2, b3) {
+			t.Errorf("Clauses diverge for %q:\n%+v\n%+v", u1, b2, b3)
+		}
+	})
+}
+
+// addressesNothing reports whether b has neither a host nor a dataset beyond slice notation
+// and slashes.
+func addressesNothing(b *Banquet) bool {
+	dataset := strings.Trim(strings.TrimPrefix(stripSlices(b.DataSetPath), "./"), "/")
+	return dataset == "" && b.Host == ""
+}
+
+// sameClauses compares the parsed clauses of a and b, ignoring the URL they came from.
+func sameClauses(a, b *Banquet) bool {
+	return a.Table == b.Table && a.Schema == b.Schema && a.DataSetPath == b.DataSetPath &&
+		equalStrings(a.Select, b.Select) && equalStrings(a.Exclude, b.Exclude) && a.Distinct == b.Distinct &&
+		a.Where == b.Where && a.GroupBy == b.GroupBy && a.Having == b.Having &&
+		fmt.Sprint(a.Sorts) == fmt.Sprint(b.Sorts) && a.Limit == b.Limit && a.Offset == b.Offset && a.FromEnd == b.FromEnd &&
+		a.OutputFormat == b.OutputFormat
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"data.sqlite/users/id,name,-age", "data.sqlite;users;id,name,-age"},
+		{"data.sqlite/users", "data.sqlite;users"},
+		{"db.sqlite/mytable/col1/col2", "db.sqlite;mytable;col2"},
+		{"file.csv/col1,col2", "file.csv;;col1,col2"},
+		{"data.sqlite?table=users&select=id,name", "data.sqlite;users;id,name"},
+		{"data.sqlite/users/id[10]", "data.sqlite;users;id?limit=10&offset=0"},
+		{"data.sqlite/users/(dept)/dept,count(id)", "data.sqlite;users;dept,count%28id%29?groupby=dept"},
+		{"gs://bucket/data.sqlite/orders/id,status!=x?where=total>5", "gs://bucket/data.sqlite;orders;id,status%21=x?where=total%3E5"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		c := b.Canonicalize()
+		if got := Unparse(c); got != tt.want {
+			t.Errorf("Unparse(Canonicalize(%q)) = %q, want %q", tt.url, got, tt.want)
+		}
+		if !sameClauses(b, c) {
+			t.Errorf("Canonicalize(%q) changed the clauses:\n%+v\n%+v", tt.url, b, c)
+		}
+		if again := Unparse(c.Canonicalize()); again != tt.want {
+			t.Errorf("Canonicalize(%q) not idempotent: %q", tt.url, again)
+		}
+	}
+}
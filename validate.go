@@ -0,0 +1,250 @@
+package banquet
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ValidationError reports a field of a Banquet that is unsafe or structurally impossible.
+type ValidationError struct {
+	Field  string // Banquet field that failed, e.g. "Select" or "Where".
+	Value  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("banquet: invalid %s %q: %s", e.Field, e.Value, e.Reason)
+}
+
+// ValidateIdentifier checks that s can be a table or column name.
+// It rejects empty names, control characters (including NUL), statement separators and comment markers.
+func ValidateIdentifier(s string) error {
+	return validateIdentifier("identifier", s)
+}
+
+func validateIdentifier(field, s string) error {
+	if strings.TrimSpace(s) == "" {
+		return &ValidationError{Field: field, Value: s, Reason: "empty identifier"}
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return &ValidationError{Field: field, Value: s, Reason: "control character"}
+		}
+	}
+	for _, token := range []string{";", "--", "/*", "*/"} {
+		if strings.Contains(s, token) {
+			return &ValidationError{Field: field, Value: s, Reason: fmt.Sprintf("contains %q", token)}
+		}
+	}
+	return nil
+}
+
+// validateExpression checks raw SQL fragments such as Where and Having.
+// Ordinary whitespace is allowed but other control characters are not.
+func validateExpression(field, s string) error {
+	for _, r := range s {
+		if unicode.IsControl(r) && r != ' ' && r != '\t' && r != '\n' && r != '\r' {
+			return &ValidationError{Field: field, Value: s, Reason: "control character"}
+		}
+	}
+	return nil
+}
+
+// Validate checks every identifier and raw fragment of b and its From subquery, returning the
+// first *ValidationError found. Where and Having are rejected when ScanWhere flags a risk, and
+// selected columns missing from GroupBy as ValidateGrouping reports them.
+func Validate(b *Banquet) error {
+	if b.From != nil {
+		if err := Validate(b.From); err != nil {
+			return err
+		}
+	}
+	if b.Table != "" {
+		if err := validateIdentifier("Table", b.Table); err != nil {
+			return err
+		}
+	}
+	for _, col := range b.Select {
+		if col == "*" {
+			continue
+		}
+		if err := validateIdentifier("Select", col); err != nil {
+			return err
+		}
+	}
+	if b.OrderBy != "" {
+		if err := validateIdentifier("OrderBy", b.OrderBy); err != nil {
+			return err
+		}
+	}
+	for _, sort := range b.Sorts {
+		if err := validateIdentifier("OrderBy", sort.Column); err != nil {
+			return err
+		}
+	}
+	if b.GroupBy != "" {
+		if err := validateIdentifier("GroupBy", b.GroupBy); err != nil {
+			return err
+		}
+	}
+	if err := ValidateGrouping(b); err != nil {
+		return err
+	}
+	// The WHERE a composer emits comes from the where param and Conditions, not b.Where alone
+	for _, field := range []struct{ name, expr string }{{"Where", sqliteRenderer.Where(b)}, {"Having", b.Having}} {
+		if err := validateExpression(field.name, field.expr); err != nil {
+			return err
+		}
+		if risks := ScanWhere(field.expr); len(risks) > 0 {
+			return &ValidationError{Field: field.name, Value: field.expr, Reason: fmt.Sprintf("%s %q at %d", risks[0].Reason, risks[0].Token, risks[0].Offset)}
+		}
+	}
+	return nil
+}
+
+// Risk is a token in a raw SQL fragment that suggests an injection attempt.
+type Risk struct {
+	Token  string // The offending text, e.g. ";" or "UNION SELECT".
+	Offset int    // Byte offset of Token in the scanned string.
+	Reason string
+}
+
+// unionSelect matches UNION [ALL] SELECT regardless of case and spacing.
+var unionSelect = regexp.MustCompile(`(?i)^\bunion(\s+all)?\s+select\b`)
+
+// ScanWhere flags stacked statements (;), comments (--, /* and MySQL's #) and UNION SELECT
+// in a raw where or having fragment. Text inside quoted literals and identifiers is skipped,
+// so name = 'a;b' is fine. MySQL reads a backslash inside a literal as an escape and other
+// dialects don't, so 'a\'; DROP ... hides the ; from one reading or the other; the fragment is
+// scanned both ways and each risk reported once. It doesn't parse SQL and only catches the
+// obvious abuse; a server exposing Banquet publicly should still bind values rather than
+// trust the fragment.
+func ScanWhere(where string) []Risk {
+	risks := scanWhere(where, false)
+	for _, r := range scanWhere(where, true) {
+		if !slices.ContainsFunc(risks, func(o Risk) bool { return o.Offset == r.Offset }) {
+			risks = append(risks, r)
+		}
+	}
+	slices.SortFunc(risks, func(a, b Risk) int { return cmp.Compare(a.Offset, b.Offset) })
+	return risks
+}
+
+// scanWhere is one reading of ScanWhere, with backslash escapes inside ' and " literals when
+// backslash is set, as in MySQL.
+func scanWhere(where string, backslash bool) []Risk {
+	var risks []Risk
+	var quote byte
+	for i := 0; i < len(where); i++ {
+		c := where[i]
+		if quote != 0 {
+			if backslash && c == '\\' && quote != '`' {
+				i++
+				continue
+			}
+			// A doubled quote is an escaped quote and keeps the literal open
+			if c == quote && (i+1 >= len(where) || where[i+1] != quote) {
+				quote = 0
+			} else if c == quote {
+				i++
+			}
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ';':
+			risks = append(risks, Risk{Token: ";", Offset: i, Reason: "stacked statement"})
+		case strings.HasPrefix(where[i:], "--"), strings.HasPrefix(where[i:], "/*"):
+			risks = append(risks, Risk{Token: where[i : i+2], Offset: i, Reason: "comment"})
+			i++
+		case c == '#':
+			risks = append(risks, Risk{Token: "#", Offset: i, Reason: "comment"})
+		case (i == 0 || !isIdentChar(where[i-1])) && unionSelect.MatchString(where[i:]):
+			m := unionSelect.FindString(where[i:])
+			risks = append(risks, Risk{Token: m, Offset: i, Reason: "UNION SELECT"})
+			i += len(m) - 1
+		}
+	}
+	return risks
+}
+
+// stripComments removes -- line comments and /* */ block comments from a raw where or having
+// fragment, leaving a space in their place so the tokens around them stay apart. Text inside
+// quoted literals and identifiers is kept, so name = 'a--b' is unchanged. An unterminated
+// block comment runs to the end of the fragment.
+func stripComments(expr string) string {
+	var out strings.Builder
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if quote != 0 {
+			if c == quote && (i+1 >= len(expr) || expr[i+1] != quote) {
+				quote = 0
+			} else if c == quote {
+				out.WriteByte(c)
+				i++
+			}
+			out.WriteByte(expr[i])
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case strings.HasPrefix(expr[i:], "--"):
+			end := strings.IndexByte(expr[i:], '\n')
+			if end == -1 {
+				i = len(expr)
+			} else {
+				i += end
+			}
+			out.WriteByte(' ')
+			continue
+		case strings.HasPrefix(expr[i:], "/*"):
+			end := strings.Index(expr[i+2:], "*/")
+			if end == -1 {
+				i = len(expr)
+			} else {
+				i += end + 3
+			}
+			out.WriteByte(' ')
+			continue
+		}
+		out.WriteByte(c)
+	}
+	if out.Len() == len(expr) {
+		return expr
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// ValidateGrouping reports selected columns that are neither in GroupBy nor wrapped in one of
+// AggregateFunctions such as count(id). A scalar call like upper(country) passes only when its
+// column arguments are grouped. SQLite accepts such queries but picks an arbitrary row per group,
+// which is rarely intended. ParseBanquet records the error in Banquet.Errors, or fails with it in
+// Strict mode, and Validate returns it.
+func ValidateGrouping(b *Banquet) error {
+	if b.GroupBy == "" {
+		return nil
+	}
+	grouped := make(map[string]bool)
+	for _, col := range strings.Split(b.GroupBy, ",") {
+		grouped[strings.ToLower(strings.TrimSpace(col))] = true
+	}
+	for _, col := range b.Select {
+		if col == "*" || grouped[strings.ToLower(col)] || isAggregate(col) {
+			continue
+		}
+		if _, args, ok := scalarCall(col); ok && !slices.ContainsFunc(args, func(arg string) bool {
+			return !literalArg.MatchString(arg) && !grouped[strings.ToLower(arg)]
+		}) {
+			continue
+		}
+		return &ValidationError{Field: "Select", Value: col, Reason: "not in GROUP BY " + b.GroupBy + " and not aggregated"}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package banquet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateIdentifier(t *testing.T) {
+	valid := []string{"id", "first name", "Order Total", "naïve"}
+	for _, s := range valid {
+		if err := ValidateIdentifier(s); err != nil {
+			t.Errorf("ValidateIdentifier(%q) = %v, want nil", s, err)
+		}
+	}
+
+	invalid := []string{"", "  ", "\"); DROP TABLE x;--", "id\x00", "name\n", "a/*b*/"}
+	for _, s := range invalid {
+		err := ValidateIdentifier(s)
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("ValidateIdentifier(%q) = %v, want *ValidationError", s, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;users;id,name,-age?where=age>18")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	if err := Validate(b); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+
+	b, err = ParseBanquet("data.sqlite;users;id,x%3B--")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	var verr *ValidationError
+	if err := Validate(b); !errors.As(err, &verr) || verr.Field != "Select" {
+		t.Errorf("Validate() = %v, want Select *ValidationError", err)
+	}
+
+	b, err = ParseBanquet("data.sqlite;users?where=id=1%00")
+	if err != nil {
+		t.Fatalf("ParseBanquet failed: %v", err)
+	}
+	if err := Validate(b); !errors.As(err, &verr) || verr.Field != "Where" {
+		t.Errorf("Validate() = %v, want Where *ValidationError", err)
+	}
+}
+
+func TestValidateGrouping(t *testing.T) {
+	valid := []string{
+		"data.sqlite;users;country?groupby=country",
+		"data.sqlite;users;country,city?groupby=country,%20city",
+		"data.sqlite;users?groupby=country",
+		"data.sqlite;users;id,name",
+		"data.sqlite;users;country,count(id),round(avg(age),1)?groupby=country",
+		"data.sqlite;users;upper(country)?groupby=country",
+	}
+	for _, raw := range valid {
+		b, err := ParseBanquetWithOptions(raw, ParseOptions{Strict: true})
+		if err != nil {
+			t.Errorf("ParseBanquetWithOptions(%q, Strict) error: %v", raw, err)
+			continue
+		}
+		if err := ValidateGrouping(b); err != nil {
+			t.Errorf("ValidateGrouping(%q) error: %v", raw, err)
+		}
+	}
+
+	raw := "data.sqlite;users;id,name,country?groupby=country"
+	b, err := ParseBanquet(raw)
+	if err != nil {
+		t.Fatalf("ParseBanquet(%q) should tolerate ungrouped columns: %v", raw, err)
+	}
+	var verr *ValidationError
+	if len(b.Errors) != 1 || !errors.As(b.Errors[0], &verr) || verr.Value != "id" {
+		t.Errorf("Expected a grouping error for id in Errors, got %v", b.Errors)
+	}
+	if _, err := ParseBanquetWithOptions(raw, ParseOptions{Strict: true}); !errors.As(err, &verr) {
+		t.Errorf("Expected Strict parse to fail with a *ValidationError, got %v", err)
+	}
+	if err := Validate(b); !errors.As(err, &verr) || verr.Value != "id" {
+		t.Errorf("Validate(%q) = %v, want the grouping error for id", raw, err)
+	}
+
+	// A scalar function is not an aggregate
+	b, _ = ParseBanquet("data.sqlite;users;country,upper(name)?groupby=country")
+	if err := ValidateGrouping(b); !errors.As(err, &verr) || verr.Value != "upper(name)" {
+		t.Errorf("ValidateGrouping() = %v, want an error for upper(name)", err)
+	}
+}
+
+func TestScanWhere(t *testing.T) {
+	tests := []struct {
+		where  string
+		tokens []string
+	}{
+		{"age > 18 AND name = 'O''Brien; --x'", nil},
+		{`"weird;col" = 1`, nil},
+		{"unionized = 1", nil},
+		{"1=1; DROP TABLE users", []string{";"}},
+		{"id = 1 -- and active", []string{"--"}},
+		{"id = 1 /* hidden */", []string{"/*"}},
+		{"id = 1 UNION SELECT password FROM users", []string{"UNION SELECT"}},
+		{"id = 1 union  all\tselect 1; --", []string{"union  all\tselect", ";", "--"}},
+		// MySQL: a backslash escapes the quote, and # starts a comment
+		{`name = 'a\'; DROP TABLE users; -- '`, []string{";", ";", "--"}},
+		{`name = 'a\'' OR 1=1; --'`, []string{";", "--"}},
+		{"id = 1 # and active", []string{"#"}},
+		{`name = 'a\\' AND tag = '#1'`, nil},
+	}
+	for _, tt := range tests {
+		risks := ScanWhere(tt.where)
+		var tokens []string
+		for _, r := range risks {
+			tokens = append(tokens, r.Token)
+		}
+		if !reflect.DeepEqual(tokens, tt.tokens) {
+			t.Errorf("ScanWhere(%q) = %q, want %q", tt.where, tokens, tt.tokens)
+		}
+	}
+}
+
+func TestValidateRejectsRisks(t *testing.T) {
+	b, err := ParseBanquet("data.sqlite;users?where=id=1;DROP%20TABLE%20users")
+	if err != nil {
+		t.Fatalf("ParseBanquet error: %v", err)
+	}
+	var verr *ValidationError
+	if err := Validate(b); !errors.As(err, &verr) || verr.Field != "Where" {
+		t.Errorf("Validate() = %v, want Where *ValidationError", err)
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		url    string
+		where  string
+		having string
+	}{
+		{"data.sqlite;users?where=age>18--%20adults%20only", "age>18", ""},
+		{"data.sqlite;users?where=age>18/*%20x%20*/AND%20id=1", "age>18 AND id=1", ""},
+		{"data.sqlite;users?where=age>18%20/*%20unterminated", "age>18", ""},
+		{"data.sqlite;users?where=note='a--b'%20AND%20c='/*x*/'", "note='a--b' AND c='/*x*/'", ""},
+		{"data.sqlite;users?where=note='it''s--fine'--drop", "note='it''s--fine'", ""},
+		{"data.sqlite;users;name!=a--b", `"name" != 'a--b'`, ""},
+		{"data.sqlite;orders?having=count(*)>5--x", "", "count(*) > 5"},
+	}
+	for _, tt := range tests {
+		b, err := ParseBanquetWithOptions(tt.url, ParseOptions{StripComments: true})
+		if err != nil {
+			t.Fatalf("ParseBanquetWithOptions(%q) failed: %v", tt.url, err)
+		}
+		if b.Where != tt.where || b.Having != tt.having {
+			t.Errorf("%s: Where/Having = %q/%q, want %q/%q", tt.url, b.Where, b.Having, tt.where, tt.having)
+		}
+		if err := Validate(b); err != nil {
+			t.Errorf("%s: Validate() = %v after stripping", tt.url, err)
+		}
+	}
+
+	// Dialect renderers read the where param again and strip it the same way
+	b, _ := ParseBanquetWithOptions("data.sqlite;users;id>1?where=age>18--x", ParseOptions{StripComments: true})
+	if got, want := (Renderer{}).Where(b), "age>18 AND id > 1"; got != want {
+		t.Errorf("Renderer{}.Where() = %q, want %q", got, want)
+	}
+
+	// Off by default
+	b, _ = ParseBanquet("data.sqlite;users?where=age>18--x")
+	if b.Where != "age>18--x" {
+		t.Errorf("Where = %q, want comments kept without StripComments", b.Where)
+	}
+}
@@ -0,0 +1,295 @@
+// Package xlsxsource answers Banquet queries against Excel workbooks. The table tier names
+// the worksheet (report.xlsx;Sheet2;A,B), defaulting to the first sheet, and the sheet's
+// header row supplies the column names. Workbooks are read with archive/zip and encoding/xml
+// so the module stays dependency free; other readers can be plugged in through Workbook.
+package xlsxsource
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/darianmavgo/banquet"
+	"github.com/darianmavgo/banquet/csvsource"
+)
+
+// Workbook reads the worksheets of a spreadsheet.
+type Workbook interface {
+	// Sheets returns the worksheet names in workbook order.
+	Sheets() []string
+	// Rows returns the cell text of a worksheet, one slice per row.
+	Rows(sheet string) ([][]string, error)
+}
+
+// Load reads sheet from wb as a csvsource.Table, using the first row as the header.
+// An empty sheet selects the first worksheet.
+func Load(wb Workbook, sheet string) (*csvsource.Table, error) {
+	sheets := wb.Sheets()
+	if sheet == "" {
+		if len(sheets) == 0 {
+			return nil, fmt.Errorf("xlsxsource: workbook has no sheets")
+		}
+		sheet = sheets[0]
+	}
+	rows, err := wb.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("xlsxsource: sheet %q: missing header row", sheet)
+	}
+	header := rows[0]
+	// Pad short rows so every row has a value per column
+	for i, row := range rows[1:] {
+		if len(row) < len(header) {
+			rows[i+1] = append(row, make([]string, len(header)-len(row))...)
+		}
+	}
+	return &csvsource.Table{Header: header, Rows: rows[1:]}, nil
+}
+
+// Query runs bq against the worksheet named by its table tier. A dotted sheet name such as
+// Q1.2024 parses as a schema qualified table, so the qualified name is used.
+func Query(wb Workbook, bq *banquet.Banquet) (*banquet.Rows, error) {
+	t, err := Load(wb, bq.QualifiedTable())
+	if err != nil {
+		return nil, err
+	}
+	return t.QueryRows(bq)
+}
+
+// Source is a banquet.DataSource that reads the workbook at the Banquet's DataSetPath under Root.
+// Register it with banquet.RegisterSource("xlsx", xlsxsource.Source{Root: "data"}).
+type Source struct {
+	// Root is the directory DataSetPath is resolved in. Paths that leave it are refused,
+	// as for csvsource.Source.
+	Root string
+}
+
+// Execute implements banquet.DataSource.
+func (s Source) Execute(ctx context.Context, bq *banquet.Banquet) (*banquet.Rows, error) {
+	if s.Root == "" {
+		return nil, csvsource.ErrNoRoot
+	}
+	f, err := os.OpenInRoot(s.Root, bq.DataSetPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	wb, err := NewReader(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	return Query(wb, bq)
+}
+
+// File is a Workbook read from an .xlsx file.
+type File struct {
+	zr      *zip.Reader
+	closer  io.Closer
+	names   []string
+	targets map[string]string // sheet name to worksheet part, e.g. xl/worksheets/sheet1.xml
+	shared  []string          // shared strings table
+}
+
+// Open opens the .xlsx file called name. Close it when done.
+func Open(name string) (*File, error) {
+	rc, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := newFile(&rc.Reader)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	f.closer = rc
+	return f, nil
+}
+
+// NewReader reads an .xlsx workbook of the given size from r.
+func NewReader(r io.ReaderAt, size int64) (*File, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return newFile(zr)
+}
+
+// Close releases the underlying file, if any.
+func (f *File) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
+
+// Sheets implements Workbook.
+func (f *File) Sheets() []string {
+	return f.names
+}
+
+// Rows implements Workbook.
+func (f *File) Rows(sheet string) ([][]string, error) {
+	target, ok := f.targets[sheet]
+	if !ok {
+		return nil, fmt.Errorf("xlsxsource: unknown sheet %q", sheet)
+	}
+	var ws struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := f.decode(target, &ws); err != nil {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(ws.Rows))
+	for _, r := range ws.Rows {
+		var row []string
+		for _, c := range r.Cells {
+			col := len(row)
+			if i := columnIndex(c.Ref); i >= 0 {
+				col = i
+			}
+			if col >= maxColumns {
+				return nil, fmt.Errorf("xlsxsource: sheet %q: cell %s out of range", sheet, c.Ref)
+			}
+			for len(row) <= col {
+				row = append(row, "")
+			}
+			switch c.Type {
+			case "s":
+				i, err := strconv.Atoi(c.Value)
+				if err != nil || i < 0 || i >= len(f.shared) {
+					return nil, fmt.Errorf("xlsxsource: sheet %q: bad shared string %q in %s", sheet, c.Value, c.Ref)
+				}
+				row[col] = f.shared[i]
+			case "inlineStr":
+				row[col] = c.Inline
+			default:
+				row[col] = c.Value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func newFile(zr *zip.Reader) (*File, error) {
+	f := &File{zr: zr, targets: map[string]string{}}
+
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"id,attr"` // r:id
+		} `xml:"sheets>sheet"`
+	}
+	if err := f.decode("xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := f.decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	for _, rel := range rels.Relationships {
+		if strings.HasPrefix(rel.Target, "/") {
+			targets[rel.ID] = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			targets[rel.ID] = path.Join("xl", rel.Target)
+		}
+	}
+	for _, s := range wb.Sheets {
+		f.names = append(f.names, s.Name)
+		f.targets[s.Name] = targets[s.ID]
+	}
+
+	// Workbooks without text cells have no shared strings part
+	if f.find("xl/sharedStrings.xml") != nil {
+		var sst struct {
+			Items []struct {
+				Text string `xml:"t"`
+				Runs []struct {
+					Text string `xml:"t"`
+				} `xml:"r"`
+			} `xml:"si"`
+		}
+		if err := f.decode("xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		for _, si := range sst.Items {
+			text := si.Text
+			for _, run := range si.Runs {
+				text += run.Text
+			}
+			f.shared = append(f.shared, text)
+		}
+	}
+	return f, nil
+}
+
+// find returns the zip entry called name, or nil.
+func (f *File) find(name string) *zip.File {
+	for _, zf := range f.zr.File {
+		if zf.Name == name {
+			return zf
+		}
+	}
+	return nil
+}
+
+// decode unmarshals the XML part called name into v.
+func (f *File) decode(name string, v any) error {
+	zf := f.find(name)
+	if zf == nil {
+		return fmt.Errorf("xlsxsource: missing %s", name)
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("xlsxsource: %s: %w", name, err)
+	}
+	return nil
+}
+
+// maxColumns is the column limit of a worksheet (XFD).
+const maxColumns = 16384
+
+// columnIndex converts the letters of a cell reference such as AB12 into a zero based column.
+// It returns -1 when ref has no column letters.
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		if col > maxColumns {
+			return maxColumns
+		}
+	}
+	return col - 1
+}
@@ -0,0 +1,198 @@
+package xlsxsource
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darianmavgo/banquet"
+	"github.com/darianmavgo/banquet/csvsource"
+)
+
+// fakeWorkbook serves sheets from memory in name order.
+type fakeWorkbook struct {
+	names  []string
+	sheets map[string][][]string
+}
+
+func (w fakeWorkbook) Sheets() []string { return w.names }
+
+func (w fakeWorkbook) Rows(sheet string) ([][]string, error) {
+	rows, ok := w.sheets[sheet]
+	if !ok {
+		return nil, fmt.Errorf("unknown sheet %q", sheet)
+	}
+	return rows, nil
+}
+
+var report = fakeWorkbook{
+	names: []string{"Summary", "Sheet2", "Q1.2024"},
+	sheets: map[string][][]string{
+		"Summary": {{"total"}, {"42"}},
+		"Q1.2024": {{"total"}, {"7"}},
+		"Sheet2": {
+			{"A", "B", "C"},
+			{"1", "Ann", "x"},
+			{"2", "Bob"},
+			{"3", "Cid", "z"},
+		},
+	},
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		url    string
+		header []string
+		rows   [][]any
+	}{
+		{"report.xlsx;Sheet2;A,B", []string{"A", "B"}, [][]any{{"1", "Ann"}, {"2", "Bob"}, {"3", "Cid"}}},
+		{"report.xlsx;Sheet2;B,A>1[0:1]", []string{"B"}, [][]any{{"Bob"}}},
+		{"report.xlsx;Sheet2;C", []string{"C"}, [][]any{{"x"}, {""}, {"z"}}},
+		{"report.xlsx;Sheet2;!B", []string{"A", "C"}, [][]any{{"1", "x"}, {"2", ""}, {"3", "z"}}},
+		// A dotted sheet name reads as schema.table
+		{"report.xlsx;Q1.2024;total", []string{"total"}, [][]any{{"7"}}},
+		// Without a table tier the first sheet is read
+		{"report.xlsx;;total", []string{"total"}, [][]any{{"42"}}},
+	}
+	for _, tt := range tests {
+		bq, err := banquet.ParseBanquet(tt.url)
+		if err != nil {
+			t.Fatalf("ParseBanquet(%q) failed: %v", tt.url, err)
+		}
+		rows, err := Query(report, bq)
+		if err != nil {
+			t.Fatalf("Query(%q) failed: %v", tt.url, err)
+		}
+		if fmt.Sprint(rows.Columns) != fmt.Sprint(tt.header) || fmt.Sprint(rows.Values) != fmt.Sprint(tt.rows) {
+			t.Errorf("Query(%q) = %v %v, want %v %v", tt.url, rows.Columns, rows.Values, tt.header, tt.rows)
+		}
+	}
+
+	bq, _ := banquet.ParseBanquet("report.xlsx;Missing;A")
+	if _, err := Query(report, bq); err == nil {
+		t.Error("expected an error for an unknown sheet")
+	}
+}
+
+// buildXLSX writes a minimal workbook with one shared string sheet and one inline string sheet.
+func buildXLSX(t *testing.T) []byte {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="People" sheetId="1" r:id="rId1"/><sheet name="Sheet2" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>name</t></si><si><t>age</t></si><si><t>Ann</t></si><si><r><t>B</t></r><r><t>ob</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>34</v></c></row>
+<row r="3"><c r="A3" t="s"><v>3</v></c><c r="B3"><v>17</v></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>A</t></is></c><c r="C1" t="inlineStr"><is><t>C</t></is></c></row>
+<row r="2"><c r="C2"><v>7</v></c></row></sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFile(t *testing.T) {
+	data := buildXLSX(t)
+	f, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	if got := strings.Join(f.Sheets(), ","); got != "People,Sheet2" {
+		t.Errorf("Sheets() = %q", got)
+	}
+
+	rows, err := f.Rows("People")
+	if err != nil {
+		t.Fatalf("Rows(People) failed: %v", err)
+	}
+	if got, want := fmt.Sprint(rows), "[[name age] [Ann 34] [Bob 17]]"; got != want {
+		t.Errorf("Rows(People) = %s, want %s", got, want)
+	}
+
+	// Skipped cells are placed by their reference
+	rows, err = f.Rows("Sheet2")
+	if err != nil {
+		t.Fatalf("Rows(Sheet2) failed: %v", err)
+	}
+	if got, want := fmt.Sprintf("%q", rows), `[["A" "" "C"] ["" "" "7"]]`; got != want {
+		t.Errorf("Rows(Sheet2) = %s, want %s", got, want)
+	}
+
+	bq, err := banquet.ParseBanquet("people.xlsx;;name,age<30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Query(f, bq)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if got := fmt.Sprint(result.Values); got != "[[Bob]]" {
+		t.Errorf("Query = %s, want [[Bob]]", got)
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB12": 27, "XFD1": 16383, "1": -1} {
+		if got := columnIndex(ref); got != want {
+			t.Errorf("columnIndex(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
+
+func TestSourceRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "people.xlsx"), buildXLSX(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	bq, err := banquet.ParseBanquet("people.xlsx;People;name,age<30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := Source{Root: root}.Execute(context.Background(), bq)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := fmt.Sprint(rows.Values); got != "[[Bob]]" {
+		t.Errorf("Execute = %s, want [[Bob]]", got)
+	}
+	if _, err := (Source{Root: filepath.Join(root, "sub")}).Execute(context.Background(), mustParse(t, "../people.xlsx")); err == nil {
+		t.Error("Execute(../people.xlsx) expected an error for a path outside Root")
+	}
+	if _, err := (Source{}).Execute(context.Background(), bq); !errors.Is(err, csvsource.ErrNoRoot) {
+		t.Errorf("Execute without Root error = %v, want csvsource.ErrNoRoot", err)
+	}
+}
+
+func mustParse(t *testing.T, rawurl string) *banquet.Banquet {
+	t.Helper()
+	bq, err := banquet.ParseBanquet(rawurl)
+	if err != nil {
+		t.Fatalf("ParseBanquet(%q) failed: %v", rawurl, err)
+	}
+	return bq
+}